@@ -6,55 +6,166 @@ import (
 	"github.com/hood-chat/core/entity"
 	rp "github.com/hood-chat/core/repo"
 	st "github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 var _ IdentityAPI = (*identityAPI)(nil)
 
+var ErrNotLogin = errors.New("Not Login")
+var ErrLocked = errors.New("identity is locked")
+var ErrAlreadyLogin = errors.New("identity already exists")
+var ErrWrongPassphrase = entity.ErrWrongPassphrase
+var ErrInvalidMnemonic = entity.ErrInvalidMnemonic
+
 type IdentityRepo = rp.IRepo[entity.Identity]
 
 type identityAPI struct {
 	repo      rp.IRepo[entity.Identity]
 	identity  *entity.Identity
+	// decrypted private key, nil until SignUp or Unlock
+	sk        crypto.PrivKey
 }
 
 func NewIdentityAPI(store *st.Store) IdentityAPI {
 	repo := rp.NewIdentityRepo(store)
 	identity, err := repo.Get()
 	if err != nil {
-		return &identityAPI{repo, nil}
+		return &identityAPI{repo, nil, nil}
 	}
-	return &identityAPI{repo, &identity}
+	return &identityAPI{repo, &identity, nil}
 }
 
 func (i *identityAPI) IsLogin() bool {
 	return i.identity != nil
 }
 
-func (i *identityAPI) SignUp(name string) (*entity.Identity, error) {
-	rIdentity := i.repo
-	iden, err := entity.CreateIdentity(name)
+func (i *identityAPI) IsUnlocked() bool {
+	return i.sk != nil
+}
+
+func (i *identityAPI) SignUp(name string, passphrase string) (*entity.Identity, error) {
+	iden, err := entity.CreateIdentity(name, passphrase)
 	if err != nil {
 		return nil, err
 	}
-	err = rIdentity.Put(iden)
-	i.identity = &iden
+	return i.login(iden, passphrase)
+}
+
+// Restore recreates the identity from a recovery phrase made by Export.
+// The restored identity has the same peer ID as the exported one.
+func (i *identityAPI) Restore(name string, mnemonic string, passphrase string) (*entity.Identity, error) {
+	if i.identity != nil {
+		return nil, ErrAlreadyLogin
+	}
+	sk, err := entity.DecodeMnemonic(mnemonic)
 	if err != nil {
 		return nil, err
 	}
+	iden, err := entity.NewIdentity(name, sk, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return i.login(iden, passphrase)
+}
+
+// Export returns the recovery phrase of the identity. The passphrase is
+// asked again so an unlocked device can not leak the key silently.
+func (i *identityAPI) Export(passphrase string) (string, error) {
+	if i.identity == nil {
+		return "", ErrNotLogin
+	}
+	sk, err := i.identity.DecodePrivateKey(passphrase)
+	if err != nil {
+		return "", err
+	}
+	return entity.EncodeMnemonic(sk)
+}
+
+func (i *identityAPI) login(iden entity.Identity, passphrase string) (*entity.Identity, error) {
+	sk, err := iden.DecodePrivateKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	err = i.repo.Put(iden)
+	if err != nil {
+		return nil, err
+	}
+	i.identity = &iden
+	i.sk = sk
 	return &iden, nil
 }
 
+// Unlock decrypts the stored private key. Identities stored before key
+// encryption are re-encrypted with passphrase.
+func (i *identityAPI) Unlock(passphrase string) error {
+	if i.identity == nil {
+		return ErrNotLogin
+	}
+	sk, err := i.identity.DecodePrivateKey(passphrase)
+	if err != nil {
+		return err
+	}
+	if !i.identity.IsEncrypted() {
+		err = i.store(sk, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	i.sk = sk
+	return nil
+}
+
+func (i *identityAPI) ChangePassphrase(old string, new string) error {
+	if i.identity == nil {
+		return ErrNotLogin
+	}
+	sk, err := i.identity.DecodePrivateKey(old)
+	if err != nil {
+		return err
+	}
+	err = i.store(sk, new)
+	if err != nil {
+		return err
+	}
+	i.sk = sk
+	return nil
+}
+
+func (i *identityAPI) store(sk crypto.PrivKey, passphrase string) error {
+	iden := *i.identity
+	err := iden.EncryptPrivateKey(sk, passphrase)
+	if err != nil {
+		return err
+	}
+	err = i.repo.Put(iden)
+	if err != nil {
+		return err
+	}
+	i.identity = &iden
+	return nil
+}
+
 func (i *identityAPI) Get() (entity.Identity, error) {
 	if i.identity==nil {
-		return entity.Identity{}, errors.New("Not Login")
+		return entity.Identity{}, ErrNotLogin
 	}
 	return *i.identity, nil
 }
 
 func (i *identityAPI) PeerID() (peer.ID, error) {
 	if i.identity==nil {
-		return peer.ID(""), errors.New("Not Login")
+		return peer.ID(""), ErrNotLogin
 	}
 	return i.identity.PeerID()
-}
\ No newline at end of file
+}
+
+func (i *identityAPI) PrivKey() (crypto.PrivKey, error) {
+	if i.identity == nil {
+		return nil, ErrNotLogin
+	}
+	if i.sk == nil {
+		return nil, ErrLocked
+	}
+	return i.sk, nil
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hood-chat/core/entity"
+	rp "github.com/hood-chat/core/repo"
+	st "github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityPassphrase(t *testing.T) {
+	s, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	id, err := NewIdentityAPI(s).SignUp("h1", "first")
+	require.NoError(t, err)
+	require.True(t, id.IsEncrypted())
+
+	// reopen the identity as it happens after an app restart
+	api := NewIdentityAPI(s)
+	require.True(t, api.IsLogin())
+	require.False(t, api.IsUnlocked())
+	_, err = api.PrivKey()
+	require.ErrorIs(t, err, ErrLocked)
+
+	err = api.Unlock("wrong")
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+	err = api.Unlock("first")
+	require.NoError(t, err)
+	sk, err := api.PrivKey()
+	require.NoError(t, err)
+	pid, err := api.PeerID()
+	require.NoError(t, err)
+	require.True(t, pid.MatchesPrivateKey(sk))
+
+	// change passphrase
+	err = api.ChangePassphrase("wrong", "second")
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+	err = api.ChangePassphrase("first", "second")
+	require.NoError(t, err)
+	api = NewIdentityAPI(s)
+	require.ErrorIs(t, api.Unlock("first"), ErrWrongPassphrase)
+	require.NoError(t, api.Unlock("second"))
+}
+
+func TestIdentityLegacyKey(t *testing.T) {
+	s, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	// identities created before key encryption store a plain base64 key
+	iden, err := entity.CreateIdentity("h1", "")
+	require.NoError(t, err)
+	sk, err := iden.DecodePrivateKey("")
+	require.NoError(t, err)
+	skbytes, err := crypto.MarshalPrivateKey(sk)
+	require.NoError(t, err)
+	iden.PrivKey = base64.StdEncoding.EncodeToString(skbytes)
+	require.NoError(t, rp.NewIdentityRepo(s).Put(iden))
+
+	api := NewIdentityAPI(s)
+	require.NoError(t, api.Unlock("new passphrase"))
+
+	stored, err := NewIdentityAPI(s).Get()
+	require.NoError(t, err)
+	require.True(t, stored.IsEncrypted())
+	_, err = stored.DecodePrivateKey("other")
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+	_, err = stored.DecodePrivateKey("new passphrase")
+	require.NoError(t, err)
+}
+
+func TestIdentityExportRestore(t *testing.T) {
+	s1, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s1.Close()
+	api1 := NewIdentityAPI(s1)
+	id1, err := api1.SignUp("h1", "first")
+	require.NoError(t, err)
+
+	_, err = api1.Export("wrong")
+	require.ErrorIs(t, err, ErrWrongPassphrase)
+	words, err := api1.Export("first")
+	require.NoError(t, err)
+	require.Len(t, strings.Fields(words), entity.MnemonicWords)
+
+	s2, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s2.Close()
+	api2 := NewIdentityAPI(s2)
+
+	// a typo is caught by the checksum
+	typo := strings.Fields(words)
+	if typo[0] == "able" {
+		typo[0] = "acid"
+	} else {
+		typo[0] = "able"
+	}
+	_, err = api2.Restore("h1", strings.Join(typo, " "), "second")
+	require.ErrorIs(t, err, ErrInvalidMnemonic)
+
+	id2, err := api2.Restore("h1", words, "second")
+	require.NoError(t, err)
+	require.Equal(t, id1.ID, id2.ID)
+	require.True(t, api2.IsUnlocked())
+
+	_, err = api2.Restore("h1", words, "second")
+	require.ErrorIs(t, err, ErrAlreadyLogin)
+
+	api2 = NewIdentityAPI(s2)
+	require.NoError(t, api2.Unlock("second"))
+	pid, err := api2.PeerID()
+	require.NoError(t, err)
+	require.Equal(t, id1.ID.String(), pid.String())
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"mime"
+	"path/filepath"
+	"time"
+
+	"github.com/hood-chat/core/blob"
+	"github.com/hood-chat/core/entity"
+)
+
+// how long a download of one attachment may take
+var attachmentTimeout = 10 * time.Minute
+
+// SendFiles sends a message with the files at paths attached. The files are
+// copied to the blob store, recipients fetch them from us.
+func (c *Chat) SendFiles(chatID entity.ID, content string, paths []string) (*entity.Message, error) {
+	atts := make([]entity.Attachment, 0, len(paths))
+	for _, p := range paths {
+		hash, size, err := c.blobs.Store().Import(p)
+		if err != nil {
+			return nil, err
+		}
+		mt := mime.TypeByExtension(filepath.Ext(p))
+		if mt == "" {
+			mt = "application/octet-stream"
+		}
+		atts = append(atts, entity.Attachment{Hash: hash, Name: filepath.Base(p), Mime: mt, Size: size})
+	}
+	return c.send(chatID, entity.Message{Text: content, Attachments: atts})
+}
+
+// Attachment returns the local file of a downloaded attachment.
+func (c *Chat) Attachment(hash string) (string, error) {
+	return c.blobs.Store().Path(hash)
+}
+
+// FetchAttachments downloads the missing attachments of a message from its
+// author, an interrupted download resumes where it stopped.
+func (c *Chat) FetchAttachments(msgID entity.ID) error {
+	msg, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return err
+	}
+	from, err := msg.Author.PeerID()
+	if err != nil {
+		return err
+	}
+	for _, a := range msg.Attachments {
+		ctx, cancel := context.WithTimeout(context.Background(), attachmentTimeout)
+		err = c.blobs.Fetch(ctx, from, a.Hash, a.Size)
+		cancel()
+		if err != nil && err != blob.ErrInProgress {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hood-chat/core/pb"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-msgio/pbio"
+)
+
+var log = logging.Logger("msgr-core-blob")
+
+var ErrInProgress = errors.New("blob is already being fetched")
+var ErrUnexpectedChunk = errors.New("blob chunk does not continue the download")
+var ErrTooLarge = errors.New("blob is larger than announced")
+var ErrInvalidSize = errors.New("blob size must be announced")
+
+const ID = protocol.ID("/chat/blob/0.0.1")
+
+const ServiceName = "chat.blob"
+
+// ChunkSize is the most data sent in one chunk.
+const ChunkSize = 64 * 1024
+
+const maxMsgSize = ChunkSize + 1024
+
+// a stream is dropped when no chunk moves for this long
+var chunkTimeout = 30 * time.Second
+
+// Progress reports a download, Err is set when it stopped.
+type Progress struct {
+	Hash     string
+	Received int64
+	Size     int64
+	Done     bool
+	Err      error
+}
+
+// Service serves blobs to the peers allowed to fetch them and fetches
+// blobs from other peers in chunks. An interrupted fetch resumes where it
+// stopped.
+type Service struct {
+	host  host.Host
+	store *Store
+	// whether p may fetch hash
+	allow    func(p peer.ID, hash string) bool
+	progress func(Progress)
+	mux      sync.Mutex
+	fetching map[string]bool
+}
+
+// NewService serves the blob protocol on h, progress is called as downloads
+// move on and may be nil.
+func NewService(h host.Host, s *Store, allow func(p peer.ID, hash string) bool, progress func(Progress)) *Service {
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+	srv := &Service{
+		host:     h,
+		store:    s,
+		allow:    allow,
+		progress: progress,
+		fetching: make(map[string]bool),
+	}
+	h.SetStreamHandler(ID, srv.handle)
+	return srv
+}
+
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+func (s *Service) Close() {
+	s.host.RemoveStreamHandler(ID)
+}
+
+func (s *Service) handle(str network.Stream) {
+	defer str.Close()
+	from := str.Conn().RemotePeer()
+	if err := str.Scope().SetService(ServiceName); err != nil {
+		str.Reset()
+		return
+	}
+	if err := str.Scope().ReserveMemory(maxMsgSize, network.ReservationPriorityAlways); err != nil {
+		str.Reset()
+		return
+	}
+	defer str.Scope().ReleaseMemory(maxMsgSize)
+
+	str.SetReadDeadline(time.Now().Add(chunkTimeout))
+	rd := pbio.NewDelimitedReader(str, 1024)
+	defer rd.Close()
+	req := new(pb.BlobRequest)
+	err := rd.ReadMsg(req)
+	if err != nil {
+		str.Reset()
+		return
+	}
+	hash := req.GetHash()
+	if s.allow == nil || !s.allow(from, hash) {
+		log.Debugf("blob %s refused to %s", hash, from)
+		str.Reset()
+		return
+	}
+	f, err := s.store.Open(hash)
+	if err != nil {
+		str.Reset()
+		return
+	}
+	defer f.Close()
+	offset := req.GetOffset()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		str.Reset()
+		return
+	}
+
+	wr := pbio.NewDelimitedWriter(str)
+	defer wr.Close()
+	buf := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			str.Reset()
+			return
+		}
+		str.SetWriteDeadline(time.Now().Add(chunkTimeout))
+		err = wr.WriteMsg(&pb.BlobChunk{Offset: offset, Data: buf[:n], Last: last})
+		if err != nil {
+			str.Reset()
+			return
+		}
+		offset += int64(n)
+		if last {
+			return
+		}
+	}
+}
+
+// Fetch downloads hash from p unless it is already stored, size is the
+// announced size of the blob and no more is received.
+func (s *Service) Fetch(ctx context.Context, p peer.ID, hash string, size int64) error {
+	if s.store.Has(hash) {
+		return nil
+	}
+	if size <= 0 {
+		return ErrInvalidSize
+	}
+	s.mux.Lock()
+	if s.fetching[hash] {
+		s.mux.Unlock()
+		return ErrInProgress
+	}
+	s.fetching[hash] = true
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.fetching, hash)
+		s.mux.Unlock()
+	}()
+
+	received, err := s.fetch(ctx, p, hash, size)
+	s.progress(Progress{Hash: hash, Received: received, Size: size, Done: err == nil, Err: err})
+	return err
+}
+
+func (s *Service) fetch(ctx context.Context, p peer.ID, hash string, size int64) (int64, error) {
+	f, offset, err := s.store.partial(hash)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	str, err := s.host.NewStream(network.WithUseTransient(ctx, "blob"), p, ID)
+	if err != nil {
+		return offset, err
+	}
+	defer str.Close()
+	if err := str.Scope().ReserveMemory(maxMsgSize, network.ReservationPriorityAlways); err != nil {
+		str.Reset()
+		return offset, err
+	}
+	defer str.Scope().ReleaseMemory(maxMsgSize)
+
+	wr := pbio.NewDelimitedWriter(str)
+	defer wr.Close()
+	err = wr.WriteMsg(&pb.BlobRequest{Hash: hash, Offset: offset})
+	if err != nil {
+		str.Reset()
+		return offset, err
+	}
+	rd := pbio.NewDelimitedReader(str, maxMsgSize)
+	defer rd.Close()
+	for {
+		str.SetReadDeadline(time.Now().Add(chunkTimeout))
+		chunk := new(pb.BlobChunk)
+		err = rd.ReadMsg(chunk)
+		if err != nil {
+			str.Reset()
+			return offset, err
+		}
+		if chunk.GetOffset() != offset {
+			str.Reset()
+			return offset, ErrUnexpectedChunk
+		}
+		if offset+int64(len(chunk.GetData())) > size {
+			str.Reset()
+			return offset, ErrTooLarge
+		}
+		_, err = f.Write(chunk.GetData())
+		if err != nil {
+			str.Reset()
+			return offset, err
+		}
+		offset += int64(len(chunk.GetData()))
+		if chunk.GetLast() {
+			break
+		}
+		s.progress(Progress{Hash: hash, Received: offset, Size: size})
+	}
+	return offset, s.store.commit(hash)
+}
@@ -0,0 +1,97 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T) *Store {
+	s, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	return s
+}
+
+func TestFetch(t *testing.T) {
+	mn, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	defer mn.Close()
+	hosts := mn.Hosts()
+
+	data := make([]byte, 3*ChunkSize+100)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	src := newStore(t)
+	hash, size, err := src.Import(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), size)
+
+	allowed := false
+	NewService(hosts[0], src, func(p peer.ID, h string) bool { return allowed && h == hash }, nil)
+	dst := newStore(t)
+	var progress []Progress
+	svc := NewService(hosts[1], dst, nil, func(p Progress) { progress = append(progress, p) })
+	ctx := context.Background()
+
+	require.Error(t, svc.Fetch(ctx, hosts[0].ID(), hash, size))
+	require.False(t, dst.Has(hash))
+
+	// resumes after the first chunk
+	allowed = true
+	f, _, err := dst.partial(hash)
+	require.NoError(t, err)
+	_, err = f.Write(data[:ChunkSize])
+	require.NoError(t, err)
+	f.Close()
+	progress = nil
+	require.NoError(t, svc.Fetch(ctx, hosts[0].ID(), hash, size))
+	got, err := os.ReadFile(filepath.Join(dst.dir, hash))
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(data, got))
+	require.Equal(t, int64(2*ChunkSize), progress[0].Received)
+	last := progress[len(progress)-1]
+	require.True(t, last.Done)
+	require.Equal(t, size, last.Received)
+}
+
+func TestFetchCorrupt(t *testing.T) {
+	mn, err := mocknet.FullMeshConnected(2)
+	require.NoError(t, err)
+	defer mn.Close()
+	hosts := mn.Hosts()
+
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	require.NoError(t, os.WriteFile(path, []byte("the document"), 0600))
+	src := newStore(t)
+	hash, size, err := src.Import(path)
+	require.NoError(t, err)
+	NewService(hosts[0], src, func(peer.ID, string) bool { return true }, nil)
+
+	// a broken partial download is dropped
+	dst := newStore(t)
+	f, _, err := dst.partial(hash)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("XXX"))
+	require.NoError(t, err)
+	f.Close()
+	svc := NewService(hosts[1], dst, nil, nil)
+	// the announced size bounds the download
+	require.ErrorIs(t, svc.Fetch(context.Background(), hosts[0].ID(), hash, 0), ErrInvalidSize)
+	require.ErrorIs(t, svc.Fetch(context.Background(), hosts[0].ID(), hash, -1), ErrInvalidSize)
+	require.ErrorIs(t, svc.Fetch(context.Background(), hosts[0].ID(), hash, size-1), ErrTooLarge)
+	require.ErrorIs(t, svc.Fetch(context.Background(), hosts[0].ID(), hash, size+3), ErrCorrupt)
+	require.False(t, dst.Has(hash))
+	require.NoError(t, svc.Fetch(context.Background(), hosts[0].ID(), hash, size))
+	require.True(t, dst.Has(hash))
+
+	require.False(t, dst.Has("../"+hash))
+}
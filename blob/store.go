@@ -0,0 +1,149 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotFound = errors.New("blob not found")
+var ErrInvalidHash = errors.New("blob hash is not a hex sha256")
+var ErrCorrupt = errors.New("blob content does not match its hash")
+
+// suffix of downloads in progress
+const partSuffix = ".part"
+
+// Store keeps blobs as files named by the hex sha256 of their content.
+// Downloads are written next to them and renamed once verified.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+func validHash(hash string) bool {
+	b, err := hex.DecodeString(hash)
+	return err == nil && len(b) == sha256.Size
+}
+
+// Import copies the file at path into the store and returns its hash and
+// size.
+func (s *Store) Import(path string) (string, int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(s.dir, "import-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	err = os.Rename(tmp.Name(), s.path(hash))
+	if err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash)
+}
+
+func (s *Store) Has(hash string) bool {
+	if !validHash(hash) {
+		return false
+	}
+	_, err := os.Stat(s.path(hash))
+	return err == nil
+}
+
+// Path returns the file of a complete blob.
+func (s *Store) Path(hash string) (string, error) {
+	if !s.Has(hash) {
+		return "", ErrNotFound
+	}
+	return s.path(hash), nil
+}
+
+func (s *Store) Open(hash string) (*os.File, error) {
+	p, err := s.Path(hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// partial opens the download of hash for appending and returns how much
+// of it was already received.
+func (s *Store) partial(hash string) (*os.File, int64, error) {
+	if !validHash(hash) {
+		return nil, 0, ErrInvalidHash
+	}
+	f, err := os.OpenFile(s.path(hash)+partSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, st.Size(), nil
+}
+
+// commit verifies a finished download and moves it into the store, a
+// corrupt download is removed so the next attempt starts over.
+func (s *Store) commit(hash string) error {
+	part := s.path(hash) + partSuffix
+	f, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hash {
+		os.Remove(part)
+		return ErrCorrupt
+	}
+	return os.Rename(part, s.path(hash))
+}
+
+// Remove deletes a blob and its unfinished download.
+func (s *Store) Remove(hash string) error {
+	if !validHash(hash) {
+		return ErrInvalidHash
+	}
+	err := os.Remove(s.path(hash) + partSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Remove(s.path(hash))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hood-chat/core/blob"
 	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
 	"github.com/hood-chat/core/protocol"
 	rp "github.com/hood-chat/core/repo"
 	st "github.com/hood-chat/core/store"
@@ -13,22 +15,32 @@ import (
 
 var _ ChatAPI = (*Chat)(nil)
 
+var ErrInvalidReceipt = errors.New("receipt does not match a message sent to its sender")
+var ErrNotResendable = errors.New("only my failed messages can be resent")
+var ErrNotViaTopic = errors.New("group messages are only taken from the group topic")
+
 type ChatRepo = rp.IRepo[entity.ChatInfo]
-type MessageRepo = rp.IRepo[entity.Message]
+type MessageRepo = rp.MessageRepo
 
 type Chat struct {
 	chRepo   ChatRepo
 	mRepo    MessageRepo
 	book     ContactBookAPI
 	pms      DirectService
-	// gps      PubSubService
+	gps      PubSubService
 	Identity IdentityAPI
+	bus      Bus
+	reqRepo  rp.ChatRequestRepo
+	invRepo  rp.InvitationRepo
+	histRepo rp.HistoryRepo
+	blobs    *blob.Service
+	signals  *signals
 }
 
-func NewChatAPI(store *st.Store, b ContactBookAPI, p DirectService, i IdentityAPI) ChatAPI {
+func NewChatAPI(store *st.Store, b ContactBookAPI, p DirectService, g PubSubService, i IdentityAPI, bus Bus, blobs *blob.Service) ChatAPI {
 	ch := rp.NewChatRepo(store)
 	m := rp.NewMessageRepo(store)
-	return &Chat{ch, m, b, p, i}
+	return &Chat{ch, m, b, p, g, i, bus, rp.NewChatRequestRepo(store), rp.NewInvitationRepo(store), rp.NewHistoryRepo(store), blobs, newSignals(bus)}
 }
 
 func (c *Chat) ChatInfo(id entity.ID) (entity.ChatInfo, error) {
@@ -41,10 +53,10 @@ func (c *Chat) ChatInfo(id entity.ID) (entity.ChatInfo, error) {
 	return chat, nil
 }
 
-func (c *Chat) ChatInfos(skip int, limit int) (entity.ChatSlice, error) {
+func (c *Chat) ChatInfos(page entity.Page) (entity.ChatPage, error) {
 	rChat := c.chRepo
-	opt := rp.NewOption(skip, limit)
-	return rChat.GetAll(opt)
+	chats, info, err := rChat.GetAll(page, nil)
+	return entity.ChatPage{Items: chats, PageInfo: info}, err
 }
 
 func (c *Chat) New(opt NewChatOpt) (entity.ChatInfo, error) {
@@ -60,21 +72,27 @@ func (c *Chat) New(opt NewChatOpt) (entity.ChatInfo, error) {
 		err = c.chRepo.Add(chat)
 		return chat, err
 	case entity.Group:
-		members := append(opt.Members, *me.ToContact())
+		// copy, appending to opt.Members could overwrite the caller's slice
+		members := append(append([]entity.Contact{}, opt.Members...), *me.ToContact())
 		chat := entity.NewGroupChat(*opt.Name, members, []entity.Contact{*me.ToContact()})
 		err := c.chRepo.Add(chat)
-		// c.gps.Join(chat.ID, members)
-		return chat, err
+		if err != nil {
+			return chat, err
+		}
+		c.gps.Join(chat.ID, chat.Members)
+		return chat, nil
 	default:
 		return entity.ChatInfo{}, errors.New("type not supported")
 	}
 
 }
 
-func (c *Chat) Messages(chatID entity.ID, skip int, limit int) (entity.MessageSlice, error) {
-	opt := rp.NewOption(skip, limit)
-	opt.AddFilter("ChatID", string(chatID))
-	return c.mRepo.GetAll(opt)
+// Messages returns a page of the messages of a chat, the newest first. The
+// page after the last message of a page holds older messages, the page
+// before its first message holds newer ones.
+func (c *Chat) Messages(chatID entity.ID, page entity.Page) (entity.MessagePage, error) {
+	msgs, info, err := c.mRepo.GetAll(page, rp.Filter{"ChatID": string(chatID)})
+	return entity.MessagePage{Items: msgs, PageInfo: info}, err
 }
 
 func (c *Chat) Message(ID entity.ID) (entity.Message, error) {
@@ -103,6 +121,11 @@ func (c *Chat) Find(opt SearchChatOpt) (entity.ChatSlice, error) {
 }
 
 func (c *Chat) Send(chatID entity.ID, content string) (*entity.Message, error) {
+	return c.send(chatID, entity.Message{Text: content})
+}
+
+// send signs and sends draft, the text and references it carries are kept.
+func (c *Chat) send(chatID entity.ID, draft entity.Message) (*entity.Message, error) {
 	me, err := c.Identity.Get()
 	if err != nil {
 		return nil, err
@@ -114,15 +137,27 @@ func (c *Chat) Send(chatID entity.ID, content string) (*entity.Message, error) {
 		log.Errorf("Can not get chat %s", err.Error())
 		return nil, err
 	}
+	if !chat.IsMember(me.ID) {
+		return nil, ErrNotMember
+	}
 
-	msg := entity.Message{
-		ID:        entity.ID(uuid.New().String()),
-		ChatID:    chatID,
-		CreatedAt: time.Now().UTC().Unix(),
-		Text:      content,
-		Status:    entity.Pending,
-		Author:    *me.ToContact(),
-		ChatType:  chat.Type,
+	msg := draft
+	msg.ID = entity.ID(uuid.New().String())
+	msg.ChatID = chatID
+	msg.CreatedAt = time.Now().UTC().Unix()
+	msg.Status = entity.Pending
+	msg.Author = *me.ToContact()
+	msg.ChatType = chat.Type
+	if chat.Expiry > 0 {
+		msg.ExpiresAt = msg.CreatedAt + chat.Expiry
+	}
+	sk, err := c.Identity.PrivKey()
+	if err != nil {
+		return nil, err
+	}
+	err = msg.Sign(sk)
+	if err != nil {
+		return nil, err
 	}
 	rmsg := c.mRepo
 	err = rmsg.Add(msg)
@@ -132,7 +167,10 @@ func (c *Chat) Send(chatID entity.ID, content string) (*entity.Message, error) {
 	}
 
 	switch chat.Type {
-	case entity.Private, entity.Group:
+	case entity.Group:
+		c.gps.Send(PubSubEnvelop{Topic: chatID.String(), Message: msg, CreatedAt: msg.CreatedAt})
+		return &msg, nil
+	case entity.Private:
 		for _, to := range chat.Members {
 			if to.ID != msg.Author.ID {
 				log.Debugf("outbox message")
@@ -149,8 +187,12 @@ func (c *Chat) Send(chatID entity.ID, content string) (*entity.Message, error) {
 
 }
 
-func (c Chat) received(msg entity.Message) error {
-	_, err := c.ChatInfo(msg.ChatID)
+func (c Chat) received(msg entity.Message, viaTopic bool) error {
+	chat, err := c.ChatInfo(msg.ChatID)
+	if err != nil && (viaTopic || msg.ChatType == entity.Group) {
+		// groups are only known by invite
+		return err
+	}
 	if err != nil {
 		log.Errorf("can not find chat %s", err.Error())
 		opt := NewChatOpt{
@@ -158,28 +200,62 @@ func (c Chat) received(msg entity.Message) error {
 			[]entity.Contact{msg.Author},
 			Of(entity.Private),
 		}
-		_, err = c.New(opt)
+		chat, err = c.New(opt)
 		if err != nil {
 			log.Errorf("fail to handle new message %s", err.Error())
 			return err
 		}
 	}
+	err = checkOrigin(chat, msg, viaTopic)
+	if err != nil {
+		return err
+	}
 
+	// our setting holds even if the sender did not know it yet
+	if chat.Expiry > 0 && (msg.ExpiresAt == 0 || msg.CreatedAt+chat.Expiry < msg.ExpiresAt) {
+		msg.ExpiresAt = msg.CreatedAt + chat.Expiry
+	}
 	rmsg := c.mRepo
+	if _, err := rmsg.GetByID(msg.ID); err == nil {
+		// sender retried, it did not get our receipt
+		c.sendReceipt(msg, entity.Delivered)
+		return nil
+	}
 	err = rmsg.Add(msg)
 	if err != nil {
 		log.Errorf("Can not add message %s , %d", err.Error(), msg)
 		return err
 	}
 	log.Debugf("new message %s ", msg)
+	c.sendReceipt(msg, entity.Delivered)
+	if len(msg.Attachments) > 0 {
+		go func() {
+			err := c.FetchAttachments(msg.ID)
+			if err != nil {
+				log.Warnf("attachments of %s not fetched: %s", msg.ID, err.Error())
+			}
+		}()
+	}
+	return nil
+}
+
+// checkOrigin tells whether chat takes msg, its author has to be a member
+// and the messages of a group come only through its topic.
+func checkOrigin(chat entity.ChatInfo, msg entity.Message, viaTopic bool) error {
+	if !chat.IsMember(msg.Author.ID) {
+		return ErrNotMember
+	}
+	if (chat.Type == entity.Group) != viaTopic {
+		return ErrNotViaTopic
+	}
 	return nil
 }
 
 func (c *Chat) Seen(chatID entity.ID) error {
-	opt := rp.NewOption(0, 0)
-	opt.AddFilter("ChatID", string(chatID))
-	opt.AddFilter("Status", []entity.Status{entity.Received})
-	unread, err := c.mRepo.GetAll(opt)
+	unread, _, err := c.mRepo.GetAll(entity.Page{}, rp.Filter{
+		"ChatID": string(chatID),
+		"Status": []entity.Status{entity.Received},
+	})
 	if err != nil {
 		return err
 	}
@@ -189,16 +265,25 @@ func (c *Chat) Seen(chatID entity.ID) error {
 		if err != nil {
 			return err
 		}
+		c.sendReceipt(msg, entity.Seen)
 	}
 	return nil
 }
 
 func (c *Chat) Join(ci entity.ChatInfo) error {
-	err := c.chRepo.Add(ci)
+	_, err := c.chRepo.GetByID(ci.ID)
+	if err == nil {
+		// rejoin a group I was removed from
+		err = c.chRepo.Put(ci)
+	} else {
+		err = c.chRepo.Add(ci)
+	}
 	if err != nil {
 		return err
 	}
-	// c.gps.Join(ci.ID, ci.Admins)
+	if ci.Type == entity.Group {
+		c.gps.Join(ci.ID, ci.Members)
+	}
 	return nil
 }
 
@@ -219,6 +304,9 @@ func (c *Chat) updateMessageStatus(msgID entity.ID, status entity.Status) error
 	if err != nil {
 		return err
 	}
+	if status.Progress() < msg.Status.Progress() {
+		return nil
+	}
 	msg.Status = status
 	err = rmsg.Put(msg)
 	if err != nil {
@@ -226,3 +314,126 @@ func (c *Chat) updateMessageStatus(msgID entity.ID, status entity.Status) error
 	}
 	return nil
 }
+
+// Resend queues a failed message of mine again, its status goes back to
+// Pending until it is sent.
+func (c *Chat) Resend(msgID entity.ID) error {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	msg, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return err
+	}
+	if msg.Author.ID != me.ID || msg.Status != entity.Failed {
+		return ErrNotResendable
+	}
+	chat, err := c.chRepo.GetByID(msg.ChatID)
+	if err != nil {
+		return err
+	}
+	msg.ChatType = chat.Type
+	err = c.updateMessageStatus(msgID, entity.Pending)
+	if err != nil {
+		return err
+	}
+	event.EmitMessageChange(c.bus, entity.Pending, msgID.String())
+	c.broadcast(chat, msg)
+	return nil
+}
+
+// requeue sends again my messages that are still pending or failed.
+func (c *Chat) requeue() error {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	chats, _, err := c.chRepo.GetAll(entity.Page{}, nil)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		// left or removed, what was not sent stays unsent
+		if !chat.IsMember(me.ID) {
+			continue
+		}
+		msgs, _, err := c.mRepo.GetAll(entity.Page{}, rp.Filter{
+			"ChatID": string(chat.ID),
+			"Status": []entity.Status{entity.Pending, entity.Failed},
+		})
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if msg.Author.ID != me.ID {
+				continue
+			}
+			msg.ChatType = chat.Type
+			if chat.Type == entity.Group {
+				go c.gps.Send(PubSubEnvelop{Topic: chat.ID.String(), Message: msg, CreatedAt: msg.CreatedAt})
+				continue
+			}
+			for _, to := range chat.Members {
+				if to.ID == me.ID {
+					continue
+				}
+				n, err := NewMessageEnvelop(to, msg)
+				if err != nil {
+					continue
+				}
+				go c.pms.Send(n)
+			}
+		}
+	}
+	return nil
+}
+
+// sendReceipt tells the author of msg that it was delivered or seen.
+func (c *Chat) sendReceipt(msg entity.Message, status entity.Status) {
+	r := entity.Receipt{ChatID: msg.ChatID, MsgID: msg.ID, Status: status}
+	n, err := NewReceiptEnvelop(msg.Author, r)
+	if err != nil {
+		log.Errorf("can not send receipt %s", err.Error())
+		return
+	}
+	// do not block the caller, it may be the loop that drains the sender's events
+	go c.pms.Send(n)
+}
+
+// receipt applies a receipt sent by a member of the chat to one of my messages.
+func (c *Chat) receipt(r entity.Receipt) error {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	msg, err := c.mRepo.GetByID(r.MsgID)
+	if err != nil {
+		return err
+	}
+	if msg.Author.ID != me.ID || msg.ChatID != r.ChatID || r.From == me.ID {
+		return ErrInvalidReceipt
+	}
+	chat, err := c.chRepo.GetByID(msg.ChatID)
+	if err != nil {
+		return err
+	}
+	member := false
+	for _, m := range chat.Members {
+		if m.ID == r.From {
+			member = true
+		}
+	}
+	if !member {
+		return ErrInvalidReceipt
+	}
+	if r.Status.Progress() <= msg.Status.Progress() {
+		return nil
+	}
+	err = c.updateMessageStatus(msg.ID, r.Status)
+	if err != nil {
+		return err
+	}
+	event.EmitMessageChange(c.bus, r.Status, msg.ID.String())
+	return nil
+}
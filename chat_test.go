@@ -0,0 +1,50 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	st "github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	s, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+	chat := NewChatAPI(s, nil, nil, nil, nil, eventbus.NewBus(), nil).(*Chat)
+	alice := entity.Contact{ID: "alice", Name: "alice"}
+	bob := entity.Contact{ID: "bob", Name: "bob"}
+	group := entity.NewGroupChat("group", []entity.Contact{alice}, []entity.Contact{alice})
+	require.NoError(t, chat.chRepo.Add(group))
+	first := entity.Message{ID: "1", ChatID: group.ID, Author: alice, Text: "hi", CreatedAt: time.Now().Unix(), Status: entity.Received}
+	require.NoError(t, chat.mRepo.Add(first))
+
+	msg := entity.Message{ID: "2", ChatID: group.ID, Author: alice, CreatedAt: time.Now().Unix()}
+	rename, edit, react := msg, msg, msg
+	rename.Control = &entity.Control{Op: entity.RenameChat, Name: "mine"}
+	edit.Edit = &entity.Edit{Target: first.ID, Text: "changed"}
+	react.React = &entity.React{Target: first.ID, Emoji: "👍"}
+
+	// the changes of a group come only through its topic
+	require.ErrorIs(t, chat.control(rename, false), ErrNotViaTopic)
+	require.ErrorIs(t, chat.edited(edit, false), ErrNotViaTopic)
+	require.ErrorIs(t, chat.reacted(react, false), ErrNotViaTopic)
+	// and only from its members
+	for _, m := range []*entity.Message{&rename, &edit, &react} {
+		m.Author = bob
+	}
+	require.ErrorIs(t, chat.control(rename, true), ErrNotMember)
+	require.ErrorIs(t, chat.edited(edit, true), ErrNotMember)
+	require.ErrorIs(t, chat.reacted(react, true), ErrNotMember)
+
+	got, err := chat.Message(first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "hi", got.Text)
+	require.Empty(t, got.Reactions)
+	ci, err := chat.ChatInfo(group.ID)
+	require.NoError(t, err)
+	require.Equal(t, "group", ci.Name)
+}
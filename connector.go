@@ -7,12 +7,15 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
 type Connector interface {
 	Need(proc string, p peer.AddrInfo)
 	Done(proc string, p peer.ID)
+	// addresses of p found outside the DHT, e.g. on the local network
+	Found(p peer.AddrInfo)
 }
 
 func NewConnector(h host.Host) Connector {
@@ -21,16 +24,24 @@ func NewConnector(h host.Host) Connector {
 
 var _ Connector = (*connector)(nil)
 
+// how long a dial may take
+var dialTimeout = time.Minute
+
 type connector struct {
 	h      host.Host
 	needed *PeerSet
 	bctx   context.Context
 	cancel context.CancelFunc
+	// finds relays of peers behind NAT, nil when the host does not use relays
+	relays *Relays
 }
 
 func newConnector(h host.Host) *connector {
 	c := connector{}
 	c.h = h
+	if rh, ok := h.(RelayHost); ok {
+		c.relays = rh.Relays()
+	}
 	c.needed = NewPeerSet()
 	c.h.Network().Notify((*connectorNotifiee)(&c))
 	c.bctx = nil
@@ -77,7 +88,11 @@ func (c *connector) connect(p peer.AddrInfo) {
 	log.Debugf("try connecting to %s", p)
 	if c.h.Network().Connectedness(p.ID) != network.Connected {
 		go func(pi peer.AddrInfo) {
-			err := c.h.Connect(context.Background(), pi)
+			err := c.dial(pi)
+			if err != nil && c.relays != nil {
+				// the peer may only be reachable through its relays
+				err = c.dialRelayed(pi.ID)
+			}
 			if err != nil {
 				c.needed.Failed(pi.ID)
 				log.Debugf("failed connecting to %s", p)
@@ -87,6 +102,28 @@ func (c *connector) connect(p peer.AddrInfo) {
 	}
 }
 
+// dial connects to pi, it gives up after dialTimeout.
+func (c *connector) dial(pi peer.AddrInfo) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	return c.h.Connect(ctx, pi)
+}
+
+// dialRelayed connects to p through one of its relays, finding the relays
+// and dialing them each have dialTimeout, a failed direct dial has usually
+// used its own.
+func (c *connector) dialRelayed(p peer.ID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	relayed, err := c.relays.Find(ctx, p)
+	cancel()
+	if err != nil {
+		return err
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	return c.h.Connect(ctx, relayed)
+}
+
 func (c *connector) Need(proc string, p peer.AddrInfo) {
 	c.h.ConnManager().Protect(p.ID, proc)
 	c.needed.Add(proc, p)
@@ -100,6 +137,15 @@ func (c *connector) Done(proc string, p peer.ID) {
 	c.mayStop()
 }
 
+// Found keeps the addresses of p and dials it right away if it is needed.
+func (c *connector) Found(p peer.AddrInfo) {
+	c.h.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.TempAddrTTL)
+	if c.needed.Has(p.ID) {
+		c.needed.Force(p.ID)
+		c.connect(p)
+	}
+}
+
 type connectorNotifiee connector
 
 func (cn *connectorNotifiee) connector() *connector {
@@ -27,11 +27,11 @@ type DisconnectAbleHost interface {
 }
 
 func NewDisconnectAbleHost(t *testing.T) DisconnectAbleHost {
-	identity, err := entity.CreateIdentity("")
+	identity, err := entity.CreateIdentity("", "")
 	if err != nil {
 		panic("")
 	}
-	sk, err := identity.DecodePrivateKey("passphrase todo!")
+	sk, err := identity.DecodePrivateKey("")
 	if err != nil {
 		panic("")
 	}
@@ -51,7 +51,7 @@ type disconnectAbleHost struct {
 }
 
 func (d *disconnectAbleHost) ON(t *testing.T) {
-	sk, err := d.id.DecodePrivateKey("passphrase todo!")
+	sk, err := d.id.DecodePrivateKey("")
 	if err != nil {
 		panic("")
 	}
@@ -17,10 +17,10 @@ func NewContactBook(store *st.Store) ContactBookAPI {
 	return &ContactBook{rp.NewContactRepo(store)}
 }
 
-func (c *ContactBook) List(skip int, limit int) (entity.ContactSlice, error) {
+func (c *ContactBook) List(page entity.Page) (entity.ContactPage, error) {
 	rContact := c.repo
-	opt := rp.NewOption(skip, limit)
-	return rContact.GetAll(opt)
+	cons, info, err := rContact.GetAll(page, nil)
+	return entity.ContactPage{Items: cons, PageInfo: info}, err
 }
 
 func (c *ContactBook) Get(id entity.ID) (entity.Contact, error) {
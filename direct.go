@@ -2,62 +2,125 @@ package core
 
 import (
 	"context"
-	"math/rand"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/hood-chat/core/entity"
 	"github.com/hood-chat/core/event"
+	"github.com/hood-chat/core/mailbox"
 	"github.com/hood-chat/core/pb"
 	pl "github.com/hood-chat/core/protocol"
+	"github.com/hood-chat/core/session"
+	"github.com/libp2p/go-libp2p/core/discovery"
 	"github.com/libp2p/go-libp2p/core/host"
 
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	bf "github.com/libp2p/go-libp2p/p2p/discovery/backoff"
 
 	ma "github.com/multiformats/go-multiaddr"
+	"google.golang.org/protobuf/proto"
 )
 
 var _ DirectService = (*DirectMessaging)(nil)
 
+var ErrAuthorMismatch = errors.New("message author is not the sender")
+var ErrOffline = errors.New("peer is not connected")
+
 type DirectMessaging struct {
 	host      host.Host
 	connector Connector
-	backoff   bf.BackoffFactory
+	retry     RetryPolicy
 	input     chan *Envelop
 	outbox    OutBox
 	bus       Bus
+	sessions  *session.Manager
+	// our mailboxes, envelopes are only taken from them
+	mailboxes []peer.AddrInfo
+	// nil when the host can not find the mailboxes of others
+	disc discovery.Discovery
+	// backoff and pending retry of failed envelops by outbox key
+	mux     sync.Mutex
+	retries map[string]bf.BackoffStrategy
+	timers  map[string]*time.Timer
+	// envelops left with a mailbox, kept until the recipient's receipt
+	deposited map[string]*Envelop
+	// canceled by Stop, wg tracks the goroutines that use the outbox
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	wg      sync.WaitGroup
 }
 
 // NewDirectMessaging creates a Direct messaging service
-func NewDirectMessaging(h host.Host, ebus Bus, connector Connector, input chan *Envelop) DirectService {
+func NewDirectMessaging(h host.Host, ebus Bus, connector Connector, input chan *Envelop, sessions *session.Manager, outbox OutBox, mailboxes []peer.AddrInfo, retry RetryPolicy) DirectService {
 	dms := &DirectMessaging{}
-	var err error
 	dms.bus = ebus
 	dms.host = h
-	// register message protocol
-	pl.Message.SetHandler(h, dms.messageHandler)
+	dms.sessions = sessions
+	dms.mailboxes = mailboxes
+	if dh, ok := h.(DiscoveryHost); ok {
+		dms.disc = dh.Discovery()
+	}
+	// register message protocol, messages are only accepted end to end encrypted
+	pl.SecureMessage.SetHandler(h, dms.secureMessageHandler)
 	// register invite protocol
 	pl.Invite.SetHandler(h, dms.inviteHandler)
+	// register delivery and seen receipts
+	pl.ChatEvent.SetHandler(h, dms.chatEventHandler)
+	// envelopes kept for us while we were offline
+	pl.MailboxDeliver.SetHandler(h, dms.mailboxHandler)
+	// typing and presence, they are not queued
+	pl.Signal.SetHandler(h, dms.signalHandler)
 	log.Debug("service PMS created")
 	dms.input = input
-	dms.outbox = NewOutBox(context.Background(), Config{true, 5 * time.Minute, 1 * time.Minute})
-	dms.backoff = bf.NewPolynomialBackoff(time.Second*5, time.Second*10, bf.NoJitter, time.Second, []float64{5, 7, 10}, rand.NewSource(0))
+	dms.outbox = outbox
+	dms.retry = retry
+	dms.retries = make(map[string]bf.BackoffStrategy)
+	dms.timers = make(map[string]*time.Timer)
+	dms.deposited = make(map[string]*Envelop)
+	dms.ctx, dms.cancel = context.WithCancel(context.Background())
 	dms.connector = connector
-	// set static relay as needed connection
-	relayInfo, err := peer.AddrInfoFromString(StaticRelays[0])
-	if err != nil {
-		panic("failed to create message service: " + err.Error())
-	}
-	relayInfo.Addrs = []ma.Multiaddr{}
-	dms.connector.Need(pl.Message.GetMeta().ServiceName, *relayInfo)
 	dms.host.Network().Notify((*dmsNotifiee)(dms))
-	go dms.background(context.Background(), dms.input)
+	dms.spawn(func() { dms.background(dms.ctx, dms.input) })
+	for _, mb := range mailboxes {
+		// staying connected lets the mailbox hand over envelopes right away
+		dms.connector.Need(string(pl.MailboxFetch.ID()), mb)
+	}
+	dms.spawn(dms.resume)
 	return dms
 }
 
+// spawn runs f in a goroutine Stop waits for, f is dropped once stopped.
+func (c *DirectMessaging) spawn(f func()) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.stopped {
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		f()
+	}()
+}
+
+// resume sends again the envelops queued before the service started.
+func (c *DirectMessaging) resume() {
+	for _, p := range c.outbox.Peers() {
+		for _, e := range c.outbox.Pop(p) {
+			c.Send(e.(*Envelop))
+		}
+	}
+}
+
 func (c *DirectMessaging) Send(nvlop *Envelop) {
-	c.input <- nvlop
+	select {
+	case c.input <- nvlop:
+	case <-c.ctx.Done():
+	}
 }
 
 // openStreamAndSend opens an stream and send proto message of envelop
@@ -65,14 +128,29 @@ func (c *DirectMessaging) openStreamAndSend(nvlop *Envelop) error {
 	log.Debug("open stream and send")
 	nctx := network.WithUseTransient(context.Background(), "just a chat")
 	pi := nvlop.PeerID()
-	s, err := c.host.NewStream(nctx, pi, nvlop.Protocol)
+	pbmsg := nvlop.Message.Proto()
+	protoID := nvlop.Protocol
+	if protoID == pl.Message.ID() {
+		protoID = pl.SecureMessage.ID()
+	}
+	s, err := c.host.NewStream(nctx, pi, protoID)
 	if err != nil {
 		log.Error("send failed", err)
 		return err
 	}
-	switch msg := nvlop.Message.Proto().(type) {
-	case *pb.Message:
-		err = pl.Message.Send(s, msg)
+	// encrypted once the stream is open, a failed dial does not use a key
+	if msg, ok := pbmsg.(*pb.Message); ok {
+		sm, err := c.sealed(nvlop, msg)
+		if err != nil {
+			s.Reset()
+			log.Error("encrypt failed", err)
+			return err
+		}
+		pbmsg = sm
+	}
+	switch msg := pbmsg.(type) {
+	case *pb.SecureMessage:
+		err = pl.SecureMessage.Send(s, msg)
 		if err != nil {
 			log.Error("send failed", err)
 			return err
@@ -83,6 +161,12 @@ func (c *DirectMessaging) openStreamAndSend(nvlop *Envelop) error {
 			log.Error("send failed", err)
 			return err
 		}
+	case *pb.ChatEvent:
+		err = pl.ChatEvent.Send(s, msg)
+		if err != nil {
+			log.Error("send failed", err)
+			return err
+		}
 	}
 
 	c.sendCompleted(nvlop)
@@ -93,22 +177,16 @@ func (c *DirectMessaging) background(ctx context.Context, nvlpCh <-chan *Envelop
 	for {
 		select {
 		case m := <-c.outbox.C():
-			c.sendFailed(m.(*Envelop))
+			nvlop := m.(*Envelop)
+			c.spawn(func() { c.depositOrFail(nvlop) })
 		case nvlp := <-nvlpCh:
 			h := c.host
+			nvlp.QueuedAt = time.Now().Unix()
 
 			pi, err := nvlp.To.AdderInfo()
 			if err != nil {
 				continue
 			}
-
-			// hack to use relay v1
-			h.Peerstore().AddAddrs(pi.ID,
-				[]ma.Multiaddr{
-					ma.StringCast("/p2p/" + "12D3KooWBFpA7pCMBySBqtduBVkakVQ3bmmaeagB83WHoruBN9s9" + "/p2p-circuit/p2p/" + nvlp.To.ID.String()),
-					ma.StringCast("/p2p/" + "12D3KooWBFpA7pCMBySBqtduBVkakVQ3bmmaeagB83WHoruBN9s9" + "/p2p-circuit/p2p/" + nvlp.To.ID.String()),
-				},
-				time.Minute*5)
 			if pi.ID == c.host.ID() || pi.ID == "" {
 				continue
 			}
@@ -125,33 +203,305 @@ func (c *DirectMessaging) background(ctx context.Context, nvlpCh <-chan *Envelop
 			default:
 				c.outbox.Put(pi.ID, nvlp)
 			}
-		case e := <-ctx.Done():
-			log.Errorf("context error broke sender %v", e)
+		case <-ctx.Done():
+			return
 		}
 
 	}
 }
 
-func (c *DirectMessaging) messageHandler(msg *pb.Message) {
+// sealed returns msg encrypted for the recipient of nvlop. It is encrypted
+// once and kept with the envelop: the recipient answers nothing before it
+// gets the message, so each new encryption of a retry or deposit would
+// skip a key of its chain until the message is no longer readable.
+func (c *DirectMessaging) sealed(nvlop *Envelop, msg *pb.Message) (*pb.SecureMessage, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if nvlop.Sealed != nil {
+		return nvlop.Sealed, nil
+	}
+	sm, err := c.seal(nvlop.PeerID(), msg)
+	if err != nil {
+		return nil, err
+	}
+	nvlop.Sealed = sm
+	c.outbox.Update(nvlop.PeerID(), nvlop)
+	return sm, nil
+}
+
+// seal encrypts msg for the session with peer to.
+func (c *DirectMessaging) seal(to peer.ID, msg *pb.Message) (*pb.SecureMessage, error) {
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.sessions.Encrypt(to, b)
+}
+
+func (c *DirectMessaging) secureMessageHandler(from peer.ID, sm *pb.SecureMessage) {
+	b, err := c.sessions.Decrypt(from, sm)
+	if err != nil {
+		log.Warnf("can not decrypt message from %s: %s", from, err.Error())
+		return
+	}
+	msg := new(pb.Message)
+	err = proto.Unmarshal(b, msg)
+	if err != nil {
+		log.Warnf("invalid message from %s: %s", from, err.Error())
+		return
+	}
+	c.messageHandler(from, msg)
+}
+
+func (c *DirectMessaging) messageHandler(from peer.ID, msg *pb.Message) {
 	log.Debugf("message received ... %s", msg.GetText())
-	event.EmitNewMessage(c.bus, entity.ToMessage(msg))
+	m := entity.ToMessage(msg)
+	err := verifyMessage(from, m)
+	if err != nil {
+		log.Warnf("message %s from %s rejected: %s", m.ID, from, err.Error())
+		event.EmitRejectedMessage(c.bus, m)
+		return
+	}
+	event.EmitNewMessage(c.bus, m)
 }
 
-func (c *DirectMessaging) inviteHandler(msg *pb.Request) {
+// verifyMessage checks the message is signed by its author and the author
+// is the peer that delivered it.
+func verifyMessage(from peer.ID, m entity.Message) error {
+	if m.Author.ID.String() != from.String() {
+		return ErrAuthorMismatch
+	}
+	return m.Verify()
+}
+
+func (c *DirectMessaging) inviteHandler(from peer.ID, msg *pb.Request) {
+	if msg.GetReply() != pb.Request_None {
+		log.Debugf("invite reply received %s, %s", msg.Id, msg.Reply)
+		r := entity.ToInviteReply(msg)
+		r.From = entity.ID(from.String())
+		action := event.InviteDeclined
+		if r.Accepted {
+			action = event.InviteAccepted
+		}
+		event.EmitInvite(c.bus, action, r)
+		return
+	}
 	log.Debugf("invite received %s, name %s", msg.Id, msg.Name)
-	event.EmitInvite(c.bus, event.InviteReceived, entity.ToChatInfo(msg))
+	chat := entity.ToChatInfo(msg)
+	inviter := entity.Contact{ID: entity.ID(from.String())}
+	for _, m := range chat.Members {
+		if m.ID == inviter.ID {
+			inviter = m
+		}
+	}
+	req := entity.ChatRequest{Chat: chat, From: inviter, CreatedAt: time.Now().Unix()}
+	event.EmitInvite(c.bus, event.InviteReceived, req)
+}
+
+func (c *DirectMessaging) chatEventHandler(from peer.ID, msg *pb.ChatEvent) {
+	log.Debugf("receipt received for %s", msg.GetMsgId())
+	r := entity.ToReceipt(msg)
+	r.From = entity.ID(from.String())
+	c.receiptReceived(from, r.MsgID)
+	event.EmitReceipt(c.bus, r)
+}
+
+// Signal sends s to a member of a private chat if it is connected, a
+// signal is not worth a connection nor a retry.
+func (c *DirectMessaging) Signal(to entity.Contact, s entity.Signal) error {
+	pi, err := to.PeerID()
+	if err != nil {
+		return err
+	}
+	if c.host.Network().Connectedness(pi) != network.Connected {
+		return ErrOffline
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pl.Signal.GetMeta().MessageTimeout)
+	defer cancel()
+	str, err := c.host.NewStream(network.WithUseTransient(ctx, "just a signal"), pi, pl.Signal.ID())
+	if err != nil {
+		return err
+	}
+	return pl.Signal.Send(str, s.Proto().(*pb.Signal))
 }
 
+func (c *DirectMessaging) signalHandler(from peer.ID, msg *pb.Signal) {
+	s := entity.ToSignal(msg)
+	s.From = entity.ID(from.String())
+	s.At = time.Now().Unix()
+	event.EmitSignalReceived(c.bus, s)
+}
+
+// mailboxHandler takes an envelope kept by one of our mailboxes as if its
+// sender had delivered it.
+func (c *DirectMessaging) mailboxHandler(from peer.ID, env *pb.MailboxEnvelope) {
+	if !c.isMailbox(from) {
+		log.Warnf("envelope %s from %s is not from our mailbox", env.GetId(), from)
+		return
+	}
+	sender, err := peer.Decode(env.GetFrom())
+	if err != nil {
+		log.Warnf("invalid sender of envelope %s: %s", env.GetId(), err.Error())
+		return
+	}
+	switch protocol.ID(env.GetProtocol()) {
+	case pl.SecureMessage.ID():
+		sm := new(pb.SecureMessage)
+		if proto.Unmarshal(env.GetPayload(), sm) == nil {
+			c.secureMessageHandler(sender, sm)
+			return
+		}
+	case pl.Invite.ID():
+		req := new(pb.Request)
+		if proto.Unmarshal(env.GetPayload(), req) == nil {
+			c.inviteHandler(sender, req)
+			return
+		}
+	case pl.ChatEvent.ID():
+		ce := new(pb.ChatEvent)
+		if proto.Unmarshal(env.GetPayload(), ce) == nil {
+			c.chatEventHandler(sender, ce)
+			return
+		}
+	}
+	log.Warnf("invalid envelope %s from %s", env.GetId(), sender)
+}
+
+func (c *DirectMessaging) isMailbox(p peer.ID) bool {
+	for _, mb := range c.mailboxes {
+		if mb.ID == p {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch asks the mailbox for the envelopes kept for us.
+func (c *DirectMessaging) fetch(mb peer.AddrInfo) {
+	ctx, cancel := context.WithTimeout(context.Background(), pl.MailboxFetch.GetMeta().ConnectTimeout)
+	defer cancel()
+	err := mailbox.Fetch(ctx, c.host, mb)
+	if err != nil {
+		log.Debugf("can not fetch mailbox %s: %s", mb.ID, err.Error())
+	}
+}
+
+// depositOrFail leaves an envelop that timed out with a mailbox of its
+// recipient, it fails when the recipient has none. A deposited message
+// stays in the outbox until the recipient's receipt arrives, the mailbox
+// may drop it, and is deposited again after the retry backoff meanwhile.
+func (c *DirectMessaging) depositOrFail(nvlop *Envelop) {
+	err := c.deposit(nvlop)
+	if err != nil {
+		log.Debugf("envelop %s not deposited: %s", nvlop.ID, err.Error())
+		c.sendFailed(nvlop)
+		c.scheduleRetry(nvlop)
+		return
+	}
+	if _, ok := nvlop.Message.(entity.Message); !ok {
+		// only messages are acknowledged by their recipient
+		c.sendCompleted(nvlop)
+		return
+	}
+	c.mux.Lock()
+	c.deposited[envelopKey(nvlop.PeerID(), nvlop.ID)] = nvlop
+	c.mux.Unlock()
+	c.connector.Done(string(nvlop.Protocol), nvlop.PeerID())
+	c.scheduleRetry(nvlop)
+}
+
+// receiptReceived ends the delivery of a message deposited for p.
+func (c *DirectMessaging) receiptReceived(p peer.ID, msgID entity.ID) {
+	key := envelopKey(p, msgID.String())
+	c.mux.Lock()
+	nvlop, ok := c.deposited[key]
+	c.mux.Unlock()
+	if ok {
+		c.sendCompleted(nvlop)
+	}
+}
+
+func (c *DirectMessaging) deposit(nvlop *Envelop) error {
+	if c.disc == nil {
+		return ErrNoDiscovery
+	}
+	to := nvlop.PeerID()
+	pbmsg := nvlop.Message.Proto()
+	protoID := nvlop.Protocol
+	if msg, ok := pbmsg.(*pb.Message); ok {
+		sm, err := c.sealed(nvlop, msg)
+		if err != nil {
+			return err
+		}
+		pbmsg = sm
+		protoID = pl.SecureMessage.ID()
+	}
+	payload, err := proto.Marshal(pbmsg)
+	if err != nil {
+		return err
+	}
+	env := &pb.MailboxEnvelope{
+		Id:        nvlop.ID,
+		To:        to.String(),
+		Protocol:  string(protoID),
+		Payload:   payload,
+		CreatedAt: nvlop.CreatedAt,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pl.MailboxDeposit.GetMeta().MessageTimeout)
+	defer cancel()
+	boxes, err := mailbox.Find(ctx, c.host, c.disc, to, 3)
+	if err != nil {
+		return err
+	}
+	for _, mb := range boxes {
+		// one copy is enough, the session would not open a second one
+		err = mailbox.Deposit(ctx, c.host, mb, env)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Stop removes the handlers and waits for the sends in progress, pending
+// retries are dropped, their envelops stay in the outbox.
 func (c *DirectMessaging) Stop() {
-	c.host.RemoveStreamHandler(pl.Message.ID())
+	c.host.RemoveStreamHandler(pl.SecureMessage.ID())
+	c.host.RemoveStreamHandler(pl.Invite.ID())
+	c.host.RemoveStreamHandler(pl.ChatEvent.ID())
+	c.host.RemoveStreamHandler(pl.MailboxDeliver.ID())
+	c.host.RemoveStreamHandler(pl.Signal.ID())
+	c.host.Network().StopNotify((*dmsNotifiee)(c))
+	c.mux.Lock()
+	c.stopped = true
+	for key, t := range c.timers {
+		t.Stop()
+		delete(c.timers, key)
+	}
+	c.mux.Unlock()
+	c.cancel()
+	c.wg.Wait()
 }
 
 func (c *DirectMessaging) sendCompleted(nvlop *Envelop) {
 	switch msg := nvlop.Message.(type) {
 	case entity.Message:
 		event.EmitMessageChange(c.bus, entity.Sent, string(msg.ID))
+	case entity.ChatInfo:
+		event.EmitInvite(c.bus, event.InviteSent, entity.Invitation{ChatID: msg.ID, To: nvlop.To, Status: entity.InviteDelivered})
 	}
+	c.outbox.Remove(nvlop.PeerID(), nvlop)
 	c.connector.Done(string(nvlop.Protocol), nvlop.PeerID())
+	c.mux.Lock()
+	key := envelopKey(nvlop.PeerID(), nvlop.ID)
+	delete(c.retries, key)
+	delete(c.deposited, key)
+	if t, ok := c.timers[key]; ok {
+		t.Stop()
+		delete(c.timers, key)
+	}
+	c.mux.Unlock()
 }
 
 func (c *DirectMessaging) sendFailed(nvlop *Envelop) {
@@ -162,17 +512,61 @@ func (c *DirectMessaging) sendFailed(nvlop *Envelop) {
 	c.connector.Done(string(nvlop.Protocol), nvlop.PeerID())
 }
 
+// scheduleRetry queues a failed envelop again after the backoff of the
+// retry policy, unless it is too old.
+func (c *DirectMessaging) scheduleRetry(nvlop *Envelop) {
+	key := envelopKey(nvlop.PeerID(), nvlop.ID)
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.stopped {
+		return
+	}
+	if c.retry.Backoff == nil || time.Since(time.Unix(nvlop.CreatedAt, 0)) > c.retry.MaxAge {
+		delete(c.retries, key)
+		return
+	}
+	s, ok := c.retries[key]
+	if !ok {
+		s = c.retry.Backoff()
+		c.retries[key] = s
+	}
+	if t, ok := c.timers[key]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(s.Delay(), func() {
+		c.mux.Lock()
+		if c.timers[key] == t {
+			delete(c.timers, key)
+		}
+		c.mux.Unlock()
+		c.spawn(func() {
+			// sent in the mean time, e.g. the peer connected
+			if !c.outbox.Failed(nvlop.PeerID(), nvlop.ID) {
+				return
+			}
+			log.Debugf("retry envelop %s", nvlop.ID)
+			if msg, ok := nvlop.Message.(entity.Message); ok {
+				event.EmitMessageChange(c.bus, entity.Pending, string(msg.ID))
+			}
+			c.Send(nvlop)
+		})
+	})
+	c.timers[key] = t
+}
+
 func (c *DirectMessaging) onConnected(pid peer.ID) {
-	msgs := c.outbox.Pop(pid)
-	go func(msgs []Expiry) {
-		for _, val := range msgs {
+	if c.isMailbox(pid) {
+		go c.fetch(peer.AddrInfo{ID: pid})
+	}
+	c.spawn(func() {
+		for _, val := range c.outbox.Pop(pid) {
 			err := c.openStreamAndSend(val.(*Envelop))
 			if err != nil {
 				c.outbox.Put(pid, val)
 			}
 		}
-	}(msgs)
-
+	})
 }
 
 type dmsNotifiee DirectMessaging
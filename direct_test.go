@@ -0,0 +1,242 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+	"github.com/hood-chat/core/pb"
+	"github.com/hood-chat/core/session"
+	st "github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	lpevent "github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	bf "github.com/libp2p/go-libp2p/p2p/discovery/backoff"
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+type mockPeer struct {
+	host  host.Host
+	sk    crypto.PrivKey
+	me    entity.Contact
+	store *st.Store
+	bus   Bus
+}
+
+// mockPeers creates n connected hosts on a mock network.
+func mockPeers(t *testing.T, n int) []*mockPeer {
+	mn := mocknet.New()
+	t.Cleanup(func() { mn.Close() })
+	peers := make([]*mockPeer, 0, n)
+	for i := 0; i < n; i++ {
+		iden, err := entity.CreateIdentity(fmt.Sprint("h", i), "")
+		require.NoError(t, err)
+		sk, err := iden.DecodePrivateKey("")
+		require.NoError(t, err)
+		h, err := mn.AddPeer(sk, ma.StringCast(fmt.Sprintf("/ip4/10.0.0.%d/tcp/4001", i+1)))
+		require.NoError(t, err)
+		s, err := st.NewStore(t.TempDir())
+		require.NoError(t, err)
+		t.Cleanup(s.Close)
+		peers = append(peers, &mockPeer{h, sk, *iden.ToContact(), s, eventbus.NewBus()})
+	}
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+	return peers
+}
+
+func (p *mockPeer) message(t *testing.T, chatID entity.ID, text string) entity.Message {
+	msg := entity.Message{
+		ID:        entity.ID(fmt.Sprint(time.Now().UnixNano())),
+		ChatID:    chatID,
+		CreatedAt: time.Now().Unix(),
+		Text:      text,
+		Author:    p.me,
+	}
+	require.NoError(t, msg.Sign(p.sk))
+	return msg
+}
+
+func nextMessageEvent(t *testing.T, sub lpevent.Subscription, name string) event.MessageEventObj {
+	for {
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.MessageEventObj)
+			if evt.GetName() == name {
+				return evt
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("no %s event emitted", name)
+		}
+	}
+}
+
+func signedMessage(t *testing.T, text string) (entity.Message, peer.ID) {
+	iden, err := entity.CreateIdentity("author", "")
+	require.NoError(t, err)
+	sk, err := iden.DecodePrivateKey("")
+	require.NoError(t, err)
+	msg := entity.Message{
+		ID:        "1",
+		ChatID:    "chat",
+		CreatedAt: time.Now().Unix(),
+		Text:      text,
+		Author:    *iden.ToContact(),
+	}
+	require.NoError(t, msg.Sign(sk))
+	pid, err := iden.PeerID()
+	require.NoError(t, err)
+	return msg, pid
+}
+
+func TestMessageSignature(t *testing.T) {
+	bus := eventbus.NewBus()
+	sub, err := bus.Subscribe(new(event.MessageEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+	dms := &DirectMessaging{bus: bus}
+
+	next := func() event.MessageEventObj {
+		select {
+		case e := <-sub.Out():
+			return e.(event.MessageEventObj)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event emitted")
+		}
+		return event.MessageEventObj{}
+	}
+
+	msg, author := signedMessage(t, "hello")
+	dms.messageHandler(author, msg.Proto().(*pb.Message))
+	require.Equal(t, event.NewMessage, next().GetName())
+
+	// text changed after signing
+	tampered := msg.Proto().(*pb.Message)
+	tampered.Text = "bye"
+	dms.messageHandler(author, tampered)
+	require.Equal(t, event.RejectedMessage, next().GetName())
+
+	// valid signature delivered by someone else
+	_, other := signedMessage(t, "")
+	dms.messageHandler(other, msg.Proto().(*pb.Message))
+	require.Equal(t, event.RejectedMessage, next().GetName())
+
+	// unsigned message
+	unsigned := msg
+	unsigned.Sig = nil
+	dms.messageHandler(author, unsigned.Proto().(*pb.Message))
+	require.Equal(t, event.RejectedMessage, next().GetName())
+}
+
+func TestDirectMessagingEncrypted(t *testing.T) {
+	peers := mockPeers(t, 2)
+	services := make([]DirectService, 0)
+	subs := make([]lpevent.Subscription, 0)
+	for _, p := range peers {
+		sessions, err := session.NewManager(p.store, p.sk)
+		require.NoError(t, err)
+		sub, err := p.bus.Subscribe(new(event.MessageEventObj))
+		require.NoError(t, err)
+		defer sub.Close()
+		subs = append(subs, sub)
+		services = append(services, NewDirectMessaging(p.host, p.bus, NewConnector(p.host), make(chan *Envelop), sessions, NewOutBox(context.Background(), outboxConfig), nil, RetryPolicy{}))
+	}
+
+	for i := 0; i < 3; i++ {
+		from, to := i%2, (i+1)%2
+		msg := peers[from].message(t, "chat", fmt.Sprint("hello ", i))
+		env, err := NewMessageEnvelop(peers[to].me, msg)
+		require.NoError(t, err)
+		services[from].Send(env)
+
+		evt := nextMessageEvent(t, subs[to], event.NewMessage)
+		got := evt.GetPayload().(entity.Message)
+		require.Equal(t, msg.Text, got.Text)
+		require.Equal(t, peers[from].me.ID, got.Author.ID)
+		evt = nextMessageEvent(t, subs[from], event.ChangeStatus)
+		require.Equal(t, entity.Sent, evt.GetAction())
+	}
+}
+
+func TestDirectMessagingRetry(t *testing.T) {
+	peers := mockPeers(t, 2)
+	sender, recipient := peers[0], peers[1]
+	sessions, err := session.NewManager(sender.store, sender.sk)
+	require.NoError(t, err)
+	sub, err := sender.bus.Subscribe(new(event.MessageEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+	outbox := NewOutBox(context.Background(), Config{true, time.Second, 500 * time.Millisecond})
+	retry := RetryPolicy{Backoff: bf.NewFixedBackoff(time.Second), MaxAge: time.Hour}
+	dms := NewDirectMessaging(sender.host, sender.bus, NewConnector(sender.host), make(chan *Envelop), sessions, outbox, nil, retry)
+
+	// the recipient does not serve the protocol yet
+	msg := sender.message(t, "chat", "hello")
+	env, err := NewMessageEnvelop(recipient.me, msg)
+	require.NoError(t, err)
+	dms.Send(env)
+	evt := nextMessageEvent(t, sub, event.ChangeStatus)
+	require.Equal(t, entity.Failed, evt.GetAction())
+
+	rsessions, err := session.NewManager(recipient.store, recipient.sk)
+	require.NoError(t, err)
+	rsub, err := recipient.bus.Subscribe(new(event.MessageEventObj))
+	require.NoError(t, err)
+	defer rsub.Close()
+	NewDirectMessaging(recipient.host, recipient.bus, NewConnector(recipient.host), make(chan *Envelop), rsessions, NewOutBox(context.Background(), outboxConfig), nil, RetryPolicy{})
+
+	evt = nextMessageEvent(t, sub, event.ChangeStatus)
+	require.Equal(t, entity.Pending, evt.GetAction())
+	evt = nextMessageEvent(t, sub, event.ChangeStatus)
+	require.Equal(t, entity.Sent, evt.GetAction())
+	evt = nextMessageEvent(t, rsub, event.NewMessage)
+	require.Equal(t, msg.ID, evt.GetPayload().(entity.Message).ID)
+}
+
+func TestSealOnce(t *testing.T) {
+	peers := mockPeers(t, 2)
+	sender, recipient := peers[0], peers[1]
+	sessions, err := session.NewManager(sender.store, sender.sk)
+	require.NoError(t, err)
+	outbox, err := NewStoreOutBox(context.Background(), outboxConfig, sender.store)
+	require.NoError(t, err)
+	dms := NewDirectMessaging(sender.host, sender.bus, NewConnector(sender.host), make(chan *Envelop), sessions, outbox, nil, RetryPolicy{}).(*DirectMessaging)
+	defer dms.Stop()
+
+	msg := sender.message(t, "chat", "hello")
+	env, err := NewMessageEnvelop(recipient.me, msg)
+	require.NoError(t, err)
+	pbmsg := msg.Proto().(*pb.Message)
+	sm, err := dms.sealed(env, pbmsg)
+	require.NoError(t, err)
+
+	// every attempt sends the same ciphertext, also after a restart
+	again, err := dms.sealed(env, pbmsg)
+	require.NoError(t, err)
+	require.Same(t, sm, again)
+	bhs, err := sender.store.Envelops()
+	require.NoError(t, err)
+	require.Len(t, bhs, 1)
+	stored, err := envelopFromStore(bhs[0])
+	require.NoError(t, err)
+	require.True(t, proto.Equal(sm, stored.Sealed))
+	again, err = dms.sealed(stored, pbmsg)
+	require.NoError(t, err)
+	require.Same(t, stored.Sealed, again)
+
+	rsessions, err := session.NewManager(recipient.store, recipient.sk)
+	require.NoError(t, err)
+	b, err := rsessions.Decrypt(sender.host.ID(), stored.Sealed)
+	require.NoError(t, err)
+	got := new(pb.Message)
+	require.NoError(t, proto.Unmarshal(b, got))
+	require.Equal(t, "hello", got.GetText())
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+)
+
+var ErrNotAuthor = errors.New("only the author can change a message")
+var ErrDeleted = errors.New("message is deleted")
+var ErrNotSent = errors.New("message is not sent yet")
+
+// Edit replaces the text of a message of mine for every member of the chat.
+func (c *Chat) Edit(msgID entity.ID, text string) (entity.Message, error) {
+	target, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return target, err
+	}
+	// the original is sent with its signature, it must not change before
+	if target.Status == entity.Pending || target.Status == entity.Failed {
+		return target, ErrNotSent
+	}
+	return c.change(target, entity.Edit{Target: msgID, Text: text})
+}
+
+// Delete removes a message. Only my own messages can be deleted for
+// everyone, the others keep it without its content.
+func (c *Chat) Delete(msgID entity.ID, forEveryone bool) error {
+	target, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return err
+	}
+	if forEveryone {
+		_, err = c.change(target, entity.Edit{Target: msgID, Delete: true})
+		return err
+	}
+	err = c.mRepo.Delete(msgID)
+	if err != nil {
+		return err
+	}
+	err = c.histRepo.Delete(msgID)
+	if err != nil {
+		return err
+	}
+	target.Deleted = true
+	event.EmitMessageUpdate(c.bus, event.MessageDeleted, target)
+	return nil
+}
+
+// History returns the earlier texts of a message, the oldest first.
+func (c *Chat) History(msgID entity.ID) (entity.MessageVersionSlice, error) {
+	return c.histRepo.GetAll(msgID)
+}
+
+// change signs an edit of target, applies it and sends it to the members.
+func (c *Chat) change(target entity.Message, ed entity.Edit) (entity.Message, error) {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return target, err
+	}
+	if target.Author.ID != me.ID {
+		return target, ErrNotAuthor
+	}
+	sk, err := c.Identity.PrivKey()
+	if err != nil {
+		return target, err
+	}
+	chat, err := c.chRepo.GetByID(target.ChatID)
+	if err != nil {
+		return target, err
+	}
+	msg := entity.Message{
+		ID:        entity.ID(uuid.New().String()),
+		ChatID:    target.ChatID,
+		CreatedAt: time.Now().UTC().Unix(),
+		Author:    *me.ToContact(),
+		ChatType:  chat.Type,
+		Edit:      &ed,
+	}
+	err = msg.Sign(sk)
+	if err != nil {
+		return target, err
+	}
+	changed, err := c.applyEdit(msg)
+	if err != nil {
+		return target, err
+	}
+	c.broadcast(chat, msg)
+	return changed, nil
+}
+
+// edited applies an edit received from a member.
+func (c *Chat) edited(msg entity.Message, viaTopic bool) error {
+	chat, err := c.chRepo.GetByID(msg.ChatID)
+	if err != nil {
+		return err
+	}
+	err = checkOrigin(chat, msg, viaTopic)
+	if err != nil {
+		return err
+	}
+	_, err = c.applyEdit(msg)
+	return err
+}
+
+// applyEdit changes the target of msg if msg is authored by the author of
+// the target. A late edit older than the last applied one is ignored.
+func (c *Chat) applyEdit(msg entity.Message) (entity.Message, error) {
+	ed := msg.Edit
+	target, err := c.mRepo.GetByID(ed.Target)
+	if err != nil {
+		return target, err
+	}
+	if target.ChatID != msg.ChatID {
+		return target, ErrWrongChat
+	}
+	if target.Author.ID != msg.Author.ID {
+		return target, ErrNotAuthor
+	}
+	if target.Deleted {
+		return target, ErrDeleted
+	}
+	if msg.CreatedAt < target.EditedAt {
+		return target, nil
+	}
+
+	if ed.Delete {
+		err = c.histRepo.Delete(target.ID)
+		if err != nil {
+			return target, err
+		}
+		target.Text = ""
+		target.Attachments = nil
+		target.Deleted = true
+	} else {
+		written := target.CreatedAt
+		if target.EditedAt > 0 {
+			written = target.EditedAt
+		}
+		err = c.histRepo.Add(entity.MessageVersion{MsgID: target.ID, Text: target.Text, CreatedAt: written})
+		if err != nil {
+			return target, err
+		}
+		target.Text = ed.Text
+	}
+	target.EditedAt = msg.CreatedAt
+	err = c.mRepo.Put(target)
+	if err != nil {
+		return target, err
+	}
+	name := event.MessageEdited
+	if target.Deleted {
+		name = event.MessageDeleted
+	}
+	event.EmitMessageUpdate(c.bus, name, target)
+	return target, nil
+}
+
+// broadcast sends msg to the other members of chat.
+func (c *Chat) broadcast(chat entity.ChatInfo, msg entity.Message) {
+	if chat.Type == entity.Group {
+		c.gps.Send(PubSubEnvelop{Topic: chat.ID.String(), Message: msg, CreatedAt: msg.CreatedAt})
+		return
+	}
+	for _, to := range chat.Members {
+		if to.ID == msg.Author.ID {
+			continue
+		}
+		n, err := NewMessageEnvelop(to, msg)
+		if err != nil {
+			continue
+		}
+		c.pms.Send(n)
+	}
+}
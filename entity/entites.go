@@ -2,7 +2,6 @@ package entity
 
 import (
 	"crypto/rand"
-	"encoding/base64"
 	"fmt"
 	"sort"
 	"strings"
@@ -25,8 +24,24 @@ const (
 	Seen
 	Received
 	Failed
+	Delivered
 )
 
+// Progress orders the statuses of an outgoing message. A message status
+// only moves forward, late acks or receipts must not roll it back.
+func (s Status) Progress() int {
+	switch s {
+	case Sent:
+		return 1
+	case Delivered:
+		return 2
+	case Seen:
+		return 3
+	default:
+		return 0
+	}
+}
+
 const (
 	Private ChatType = iota
 	Group
@@ -42,15 +57,13 @@ func (c Identity) PeerID() (peer.ID, error) {
 	return peer.Decode(string(c.ID))
 }
 
-// DecodePrivateKey is a helper to decode the users PrivateKey
+// DecodePrivateKey decrypts the users PrivateKey with passphrase.
+// It returns ErrWrongPassphrase when the passphrase does not match.
 func (i *Identity) DecodePrivateKey(passphrase string) (crypto.PrivKey, error) {
-	pkb, err := base64.StdEncoding.DecodeString(i.PrivKey)
+	pkb, err := decryptPrivateKey(i, passphrase)
 	if err != nil {
 		return nil, err
 	}
-
-	// currently storing key unencrypted. in the future we need to encrypt it.
-	// TODO(security)
 	return crypto.UnmarshalPrivateKey(pkb)
 }
 
@@ -61,37 +74,33 @@ func (i *Identity) ToContact() *Contact {
 	}
 }
 
-func CreateIdentity(name string) (Identity, error) {
-	ident := Identity{}
-
-	var sk crypto.PrivKey
-	var pk crypto.PubKey
-
+// CreateIdentity generates a new key pair and stores the private key
+// encrypted with passphrase.
+func CreateIdentity(name string, passphrase string) (Identity, error) {
 	fmt.Printf("generating ED25519 keypair...")
-	priv, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
 	if err != nil {
-		return ident, err
+		return Identity{}, err
 	}
-
-	sk = priv
-	pk = pub
-
 	fmt.Print("done\n")
 
-	// currently storing key unencrypted. in the future we need to encrypt it.
-	// TODO(security)
-	skbytes, err := crypto.MarshalPrivateKey(sk)
-	if err != nil {
-		return ident, err
-	}
-	ident.PrivKey = base64.StdEncoding.EncodeToString(skbytes)
+	return NewIdentity(name, priv, passphrase)
+}
 
-	id, err := peer.IDFromPublicKey(pk)
+// NewIdentity creates an identity for an existing private key,
+// e.g. one restored from a recovery phrase.
+func NewIdentity(name string, sk crypto.PrivKey, passphrase string) (Identity, error) {
+	ident := Identity{}
+	id, err := peer.IDFromPrivateKey(sk)
 	if err != nil {
 		return ident, err
 	}
 	ident.ID = ID(id.String())
 	ident.Name = name
+	err = ident.EncryptPrivateKey(sk, passphrase)
+	if err != nil {
+		return ident, err
+	}
 	fmt.Printf("peer identity: %s\n", ident.ID)
 	return ident, nil
 }
@@ -106,6 +115,118 @@ type Message struct {
 	Status    Status		`json:"status"`
 	Author    Contact		`json:"user"`
 	ChatType  ChatType		`json:"chatType"`
+	Sig       []byte		`json:"-"`
+	// set on group control messages, they are applied and not stored
+	Control *Control `json:"control,omitempty"`
+	// files sent with the message
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// set on edit messages, they are applied to their target and not stored
+	Edit *Edit `json:"edit,omitempty"`
+	// when the text was last edited
+	EditedAt int64 `json:"editedAt,omitempty"`
+	// deleted for everyone by its author
+	Deleted bool `json:"deleted,omitempty"`
+	// the message this one answers
+	ReplyTo *Reply `json:"replyTo,omitempty"`
+	// first message of the thread, empty outside threads
+	ThreadID ID `json:"threadId,omitempty"`
+	// set on reaction messages, they are applied to their target and not stored
+	React *React `json:"react,omitempty"`
+	// reactions of the members by emoji
+	Reactions []Reaction `json:"reactions,omitempty"`
+	// unix time the message disappears, zero keeps it
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// SearchResult is a message found by a search. Snippet is the part of the
+// text around the first match, Highlights are the matched words in it.
+type SearchResult struct {
+	Message    Message     `json:"message"`
+	Score      float64     `json:"score"`
+	Snippet    string      `json:"snippet"`
+	Highlights []Highlight `json:"highlights,omitempty"`
+}
+
+// Highlight is a matched word of a snippet, Start and End are rune offsets.
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// React adds an emoji of its author to a message, Remove takes it back.
+type React struct {
+	Target ID     `json:"target"`
+	Emoji  string `json:"emoji"`
+	Remove bool   `json:"remove,omitempty"`
+}
+
+// Reaction counts the members that reacted to a message with an emoji.
+type Reaction struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Authors []ID   `json:"authors"`
+}
+
+// Reply quotes the answered message, recipients show it even when they
+// never got the original.
+type Reply struct {
+	ID      ID      `json:"_id"`
+	Author  Contact `json:"user"`
+	Snippet string  `json:"snippet"`
+}
+
+// Edit changes a message sent earlier by the same author.
+type Edit struct {
+	Target ID     `json:"target"`
+	Text   string `json:"text,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+}
+
+// MessageVersion is an earlier text of an edited message.
+type MessageVersion struct {
+	MsgID ID     `json:"msgId"`
+	Text  string `json:"text"`
+	// when this text was written
+	CreatedAt int64 `json:"createdAt"`
+}
+
+// Attachment references a file by the hex sha256 of its content.
+type Attachment struct {
+	Hash string `json:"hash"`
+	Name string `json:"name"`
+	Mime string `json:"mime"`
+	Size int64  `json:"size"`
+}
+
+// Transfer is the progress of an attachment download.
+type Transfer struct {
+	Hash     string `json:"hash"`
+	Received int64  `json:"received"`
+	Size     int64  `json:"size"`
+	// set when the transfer failed
+	Error string `json:"error,omitempty"`
+}
+
+type ControlOp int
+
+const (
+	AddMembers ControlOp = iota
+	RemoveMembers
+	PromoteAdmins
+	DemoteAdmins
+	RenameChat
+	LeaveChat
+	SetExpiry
+)
+
+// Control is a change of a group made by an admin, or of the expiry of a
+// private chat made by a member.
+type Control struct {
+	Op      ControlOp `json:"op"`
+	Members []Contact `json:"members,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	// seconds messages are kept, zero keeps them
+	Expiry int64 `json:"expiry,omitempty"`
 }
 
 type Contact struct {
@@ -125,6 +246,45 @@ func (c Contact) PeerID() (peer.ID, error) {
 	return peer.Decode(string(c.ID))
 }
 
+// Receipt reports that a recipient got or read a message.
+type Receipt struct {
+	ChatID ID     `json:"chatId"`
+	MsgID  ID     `json:"msgId"`
+	Status Status `json:"status"`
+	// peer that sent the receipt
+	From ID `json:"from"`
+}
+
+type SignalKind int
+
+const (
+	TypingStarted SignalKind = iota
+	TypingStopped
+	Online
+	Away
+)
+
+// Signal is a typing or presence state of a peer, it is never stored.
+type Signal struct {
+	// chat of a typing signal, presence is sent to every chat
+	ChatID ID         `json:"chatId"`
+	Kind   SignalKind `json:"kind"`
+	// seconds the state holds unless it is sent again
+	TTL int64 `json:"ttl"`
+	// peer that sent the signal
+	From ID `json:"from"`
+	// unix time it was received
+	At int64 `json:"at"`
+}
+
+// Presence is the last known state of a contact.
+type Presence struct {
+	ID     ID   `json:"id"`
+	Online bool `json:"online"`
+	// unix time of the last signal of the contact, zero when never seen
+	LastSeen int64 `json:"lastSeen"`
+}
+
 var _ JsonMessage = (*ChatInfo)(nil)
 
 type ChatInfo struct {
@@ -135,6 +295,61 @@ type ChatInfo struct {
 	Type       ChatType		`json:"type"`
 	Unread     uint64		`json:"unread"`
 	LatestText string		`json:"latestText"`
+	// the latest message of the chat, without messages LatestAt is when
+	// the chat was added
+	LatestID     ID       `json:"latestId,omitempty"`
+	LatestAuthor *Contact `json:"latestAuthor,omitempty"`
+	LatestAt     int64    `json:"latestAt,omitempty"`
+	// seconds new messages are kept, zero keeps them
+	Expiry int64 `json:"expiry,omitempty"`
+}
+
+func (c ChatInfo) IsMember(id ID) bool {
+	return containsContact(c.Members, id)
+}
+
+func (c ChatInfo) IsAdmin(id ID) bool {
+	return containsContact(c.Admins, id)
+}
+
+func containsContact(cs []Contact, id ID) bool {
+	for _, c := range cs {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+type InviteStatus int
+
+const (
+	InviteDelivered InviteStatus = iota
+	InviteAccepted
+	InviteDeclined
+)
+
+// ChatRequest is a group invite received from an admin of the group, it is
+// kept until the user accepts or declines it.
+type ChatRequest struct {
+	Chat      ChatInfo `json:"chat"`
+	From      Contact  `json:"from"`
+	CreatedAt int64    `json:"createdAt"`
+}
+
+// Invitation is an invite I sent to a contact and its answer.
+type Invitation struct {
+	ChatID ID           `json:"chatId"`
+	To     Contact      `json:"to"`
+	Status InviteStatus `json:"status"`
+}
+
+// InviteReply answers a ChatRequest.
+type InviteReply struct {
+	ChatID   ID   `json:"chatId"`
+	Accepted bool `json:"accepted"`
+	// peer that answered
+	From ID `json:"from"`
 }
 
 func NewPrivateChat(creator Contact, con Contact) ChatInfo {
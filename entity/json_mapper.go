@@ -23,7 +23,29 @@ func (m *ChatInfo) Json() ([]byte, error) {
 	return json.Marshal(*m)
 }
 
+func (m *Receipt) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
 
+func (m *MessageVersion) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *Transfer) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *ChatRequest) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *Invitation) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *InviteReply) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
 
 type ChatSlice []ChatInfo
 func (m ChatSlice) Json() ([]byte, error) {
@@ -40,3 +62,50 @@ func (m ContactSlice) Json() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+type ChatRequestSlice []ChatRequest
+
+func (m ChatRequestSlice) Json() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+type InvitationSlice []Invitation
+
+func (m InvitationSlice) Json() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+type MessageVersionSlice []MessageVersion
+
+func (m MessageVersionSlice) Json() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+type SearchResultSlice []SearchResult
+
+func (m SearchResultSlice) Json() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *Page) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *MessagePage) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *ChatPage) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *ContactPage) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *ChatRequestPage) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
+
+func (m *SearchResultPage) Json() ([]byte, error) {
+	return json.Marshal(*m)
+}
@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"golang.org/x/crypto/argon2"
+)
+
+// KeyVersion is the current format of an encrypted private key.
+const KeyVersion = 1
+
+const kdfArgon2id = "argon2id"
+
+var ErrWrongPassphrase = errors.New("wrong passphrase")
+var ErrUnsupportedKey = errors.New("unsupported private key format")
+
+// KDFParams holds the argon2id cost parameters used to derive the key
+// encryption key from a passphrase.
+type KDFParams struct {
+	Time    uint32 `json:"t"`
+	Memory  uint32 `json:"m"`
+	Threads uint8  `json:"p"`
+}
+
+// DefaultKDFParams is tuned to stay usable on mobile devices.
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 2}
+
+// encryptedKey is the versioned envelope stored in Identity.PrivKey.
+type encryptedKey struct {
+	Version int       `json:"v"`
+	KDF     string    `json:"kdf"`
+	Params  KDFParams `json:"params"`
+	Salt    []byte    `json:"salt"`
+	Nonce   []byte    `json:"nonce"`
+	Cipher  []byte    `json:"cipher"`
+}
+
+// IsEncrypted reports whether the stored private key is protected by a passphrase.
+// Identities created before key encryption hold a plain base64 key.
+func (i *Identity) IsEncrypted() bool {
+	return strings.HasPrefix(i.PrivKey, "{")
+}
+
+// EncryptPrivateKey encrypts sk with a key derived from passphrase and stores
+// the result in i.PrivKey.
+func (i *Identity) EncryptPrivateKey(sk crypto.PrivKey, passphrase string) error {
+	skbytes, err := crypto.MarshalPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	ek := encryptedKey{
+		Version: KeyVersion,
+		KDF:     kdfArgon2id,
+		Params:  DefaultKDFParams,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(ek.Salt); err != nil {
+		return err
+	}
+	aead, err := keyCipher(passphrase, ek.Salt, ek.Params)
+	if err != nil {
+		return err
+	}
+	ek.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ek.Nonce); err != nil {
+		return err
+	}
+	ek.Cipher = aead.Seal(nil, ek.Nonce, skbytes, []byte(i.ID))
+	b, err := json.Marshal(ek)
+	if err != nil {
+		return err
+	}
+	i.PrivKey = string(b)
+	return nil
+}
+
+func decryptPrivateKey(i *Identity, passphrase string) ([]byte, error) {
+	if !i.IsEncrypted() {
+		return base64.StdEncoding.DecodeString(i.PrivKey)
+	}
+	ek := encryptedKey{}
+	err := json.Unmarshal([]byte(i.PrivKey), &ek)
+	if err != nil {
+		return nil, err
+	}
+	if ek.Version != KeyVersion || ek.KDF != kdfArgon2id {
+		return nil, ErrUnsupportedKey
+	}
+	aead, err := keyCipher(passphrase, ek.Salt, ek.Params)
+	if err != nil {
+		return nil, err
+	}
+	skbytes, err := aead.Open(nil, ek.Nonce, ek.Cipher, []byte(i.ID))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return skbytes, nil
+}
+
+func keyCipher(passphrase string, salt []byte, p KDFParams) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
+)
+
+// MnemonicWords is the number of words in a recovery phrase: one word per
+// byte of the 32 byte Ed25519 seed followed by a 2 byte checksum.
+const MnemonicWords = ed25519.SeedSize + mnemonicChecksum
+
+const mnemonicChecksum = 2
+
+var ErrInvalidMnemonic = errors.New("invalid recovery phrase")
+
+// EncodeMnemonic encodes the seed of an Ed25519 private key as a recovery phrase.
+func EncodeMnemonic(sk crypto.PrivKey) (string, error) {
+	if sk.Type() != pb.KeyType_Ed25519 {
+		return "", ErrUnsupportedKey
+	}
+	raw, err := sk.Raw()
+	if err != nil {
+		return "", err
+	}
+	seed := raw[:ed25519.SeedSize]
+	sum := sha256.Sum256(seed)
+	data := append(append([]byte{}, seed...), sum[:mnemonicChecksum]...)
+	words := make([]string, 0, len(data))
+	for _, b := range data {
+		words = append(words, wordList[b])
+	}
+	return strings.Join(words, " "), nil
+}
+
+// DecodeMnemonic restores the Ed25519 private key from a recovery phrase.
+// Words may be abbreviated to their first four letters.
+func DecodeMnemonic(mnemonic string) (crypto.PrivKey, error) {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	if len(words) != MnemonicWords {
+		return nil, ErrInvalidMnemonic
+	}
+	data := make([]byte, 0, len(words))
+	for _, w := range words {
+		b, ok := wordIndex(w)
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		data = append(data, b)
+	}
+	seed := data[:ed25519.SeedSize]
+	sum := sha256.Sum256(seed)
+	if !bytes.Equal(sum[:mnemonicChecksum], data[ed25519.SeedSize:]) {
+		return nil, ErrInvalidMnemonic
+	}
+	return crypto.UnmarshalEd25519PrivateKey(ed25519.NewKeyFromSeed(seed))
+}
+
+func wordIndex(w string) (byte, bool) {
+	for i, v := range wordList {
+		if v == w || (len(w) >= 4 && strings.HasPrefix(v, w)) {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
+
+// wordList has 256 words with unique four letter prefixes.
+var wordList = [256]string{
+	"able", "acid", "aged", "also", "anchor", "angle", "apple", "april",
+	"arch", "area", "army", "atom", "aunt", "auto", "away", "axis",
+	"baby", "back", "badge", "bake", "ball", "band", "bank", "barn",
+	"base", "bath", "beach", "bean", "bear", "bell", "belt", "bench",
+	"bird", "blade", "blue", "boat", "body", "bold", "bone", "book",
+	"boss", "bowl", "brave", "bread", "brick", "bridge", "broom", "brush",
+	"cabin", "cable", "cake", "calm", "camp", "candy", "cape", "card",
+	"cargo", "carpet", "castle", "cave", "chair", "chalk", "cheese", "chef",
+	"chip", "city", "claim", "clay", "cliff", "clock", "cloud", "coach",
+	"coast", "coffee", "coin", "comet", "cook", "coral", "corn", "cotton",
+	"crab", "crane", "cream", "crisp", "crown", "cube", "curve", "cycle",
+	"daisy", "dance", "dawn", "deal", "deer", "denim", "desk", "dial",
+	"dinner", "dish", "dock", "dome", "donkey", "door", "dose", "dove",
+	"dragon", "dream", "drum", "duck", "dune", "dust", "eagle", "earth",
+	"echo", "edge", "eight", "elbow", "elder", "empty", "engine", "envy",
+	"equal", "error", "essay", "event", "exit", "fabric", "face", "fairy",
+	"farm", "feast", "fence", "ferry", "field", "film", "finger", "fire",
+	"fish", "flag", "flame", "flute", "foam", "focus", "fog", "forest",
+	"fork", "fossil", "fox", "frame", "frog", "fruit", "fuel", "funny",
+	"gadget", "garden", "gate", "gear", "gentle", "ghost", "giant", "gift",
+	"ginger", "glass", "globe", "glove", "goat", "gold", "grape", "grass",
+	"gravel", "green", "group", "guitar", "habit", "hair", "hammer", "hand",
+	"harbor", "hat", "hawk", "heart", "hedge", "helmet", "hero", "hill",
+	"hobby", "honey", "hook", "horse", "hotel", "house", "humor", "hunt",
+	"ice", "idea", "igloo", "image", "inch", "index", "ink", "iron",
+	"island", "ivory", "jacket", "jaguar", "jar", "jazz", "jeans", "jelly",
+	"jewel", "job", "joke", "judge", "juice", "jump", "jungle", "kayak",
+	"kernel", "kettle", "key", "kid", "king", "kite", "kiwi", "knee",
+	"knife", "koala", "label", "ladder", "lake", "lamp", "laptop", "lava",
+	"lawn", "leaf", "lemon", "lens", "level", "lily", "lime", "lion",
+	"list", "lizard", "llama", "lobby", "lock", "logic", "loop", "lotus",
+	"lucky", "lunar", "lunch", "lyric", "magic", "magnet", "mango", "maple",
+}
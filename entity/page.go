@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// Cursor marks an item of a list, the page after or before it is read from
+// there. It holds the sort key and the id of the item so that pages do not
+// shift when items are added, callers must not build or parse it.
+type Cursor string
+
+func NewCursor(key int64, id ID) Cursor {
+	raw := strconv.FormatInt(key, 10) + "/" + id.String()
+	return Cursor(base64.RawURLEncoding.EncodeToString([]byte(raw)))
+}
+
+// Decode returns the sort key and the id of the item of c.
+func (c Cursor) Decode() (int64, ID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	key, id, ok := strings.Cut(string(raw), "/")
+	if !ok || id == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	k, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return k, ID(id), nil
+}
+
+// Page asks for the Limit items that follow After in the list, or that
+// precede Before. Without a cursor the first items are read, all of them
+// when Limit is zero.
+type Page struct {
+	After  Cursor `json:"after,omitempty"`
+	Before Cursor `json:"before,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// PageInfo tells where a page is in its list. Start and End are the cursors
+// of the first and last items of the page, empty when the page is empty.
+// More tells there are items past the page in the direction it was read.
+type PageInfo struct {
+	Start Cursor `json:"start,omitempty"`
+	End   Cursor `json:"end,omitempty"`
+	More  bool   `json:"more"`
+}
+
+type MessagePage struct {
+	Items MessageSlice `json:"items"`
+	PageInfo
+}
+
+type ChatPage struct {
+	Items ChatSlice `json:"items"`
+	PageInfo
+}
+
+type ContactPage struct {
+	Items ContactSlice `json:"items"`
+	PageInfo
+}
+
+type ChatRequestPage struct {
+	Items ChatRequestSlice `json:"items"`
+	PageInfo
+}
+
+type SearchResultPage struct {
+	Items SearchResultSlice `json:"items"`
+	PageInfo
+}
@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"encoding/base64"
+
 	"github.com/hood-chat/core/pb"
 	"google.golang.org/protobuf/proto"
 )
@@ -11,30 +13,77 @@ type ProtoMessage interface {
 
 func (m Message) Proto() proto.Message {
 	msg := m
-	return &pb.Message{
+	pbmsg := &pb.Message{
 		Text:      msg.Text,
 		Id:        msg.ID.String(),
 		ChatId:    msg.ChatID.String(),
 		CreatedAt: msg.CreatedAt,
 		Type:      "text",
-		Sig:       "",
+		Sig:       base64.StdEncoding.EncodeToString(msg.Sig),
 		Author: &pb.Contact{
 			Id:   msg.Author.ID.String(),
 			Name: msg.Author.Name,
 		},
 		ChatType: pb.CHAT_TYPES(msg.ChatType),
 	}
+	if msg.Control != nil {
+		pbmsg.Type = "control"
+		pbmsg.Control = &pb.GroupControl{
+			Op:      pb.GroupControl_Op(msg.Control.Op),
+			Members: toPbContacts(msg.Control.Members),
+			Name:    msg.Control.Name,
+			Expiry:  msg.Control.Expiry,
+		}
+	}
+	for _, a := range msg.Attachments {
+		pbmsg.Attachments = append(pbmsg.Attachments, &pb.Attachment{Hash: a.Hash, Name: a.Name, Mime: a.Mime, Size: a.Size})
+	}
+	if msg.Edit != nil {
+		pbmsg.Type = "edit"
+		pbmsg.Edit = &pb.MessageEdit{Target: msg.Edit.Target.String(), Text: msg.Edit.Text, Delete: msg.Edit.Delete}
+	}
+	if r := msg.ReplyTo; r != nil {
+		pbmsg.Reply = &pb.Reply{
+			Id:      r.ID.String(),
+			Author:  &pb.Contact{Id: r.Author.ID.String(), Name: r.Author.Name},
+			Snippet: r.Snippet,
+		}
+	}
+	pbmsg.ThreadId = msg.ThreadID.String()
+	if r := msg.React; r != nil {
+		pbmsg.Type = "reaction"
+		pbmsg.Reaction = &pb.MessageReaction{Target: r.Target.String(), Emoji: r.Emoji, Remove: r.Remove}
+	}
+	pbmsg.ExpiresAt = msg.ExpiresAt
+	return pbmsg
+}
+
+func toPbContacts(cs []Contact) []*pb.Contact {
+	res := make([]*pb.Contact, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, &pb.Contact{Id: c.ID.String(), Name: c.Name})
+	}
+	return res
+}
+
+func toContacts(cs []*pb.Contact) []Contact {
+	res := make([]Contact, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, Contact{ID(c.GetId()), c.GetName()})
+	}
+	return res
 }
 
 func ToMessage(pbmsg *pb.Message) Message {
-	mAuthorID := ID(pbmsg.Author.Id)
+	mAuthorID := ID(pbmsg.GetAuthor().GetId())
 	msgID := ID(pbmsg.GetId())
 	chatID := ID(pbmsg.ChatId)
 	con := Contact{
 		ID:   mAuthorID,
-		Name: pbmsg.Author.Name,
+		Name: pbmsg.GetAuthor().GetName(),
 	}
-	return Message{
+	sig, _ := base64.StdEncoding.DecodeString(pbmsg.GetSig())
+	msg := Message{
 		ID:        msgID,
 		ChatID:    chatID,
 		CreatedAt: pbmsg.GetCreatedAt(),
@@ -42,7 +91,35 @@ func ToMessage(pbmsg *pb.Message) Message {
 		Status:    Received,
 		Author:    con,
 		ChatType:  ChatType(pbmsg.ChatType),
+		Sig:       sig,
+	}
+	if ctl := pbmsg.GetControl(); ctl != nil {
+		msg.Control = &Control{
+			Op:      ControlOp(ctl.GetOp()),
+			Members: toContacts(ctl.GetMembers()),
+			Name:    ctl.GetName(),
+			Expiry:  ctl.GetExpiry(),
+		}
+	}
+	for _, a := range pbmsg.GetAttachments() {
+		msg.Attachments = append(msg.Attachments, Attachment{Hash: a.GetHash(), Name: a.GetName(), Mime: a.GetMime(), Size: a.GetSize()})
+	}
+	if ed := pbmsg.GetEdit(); ed != nil {
+		msg.Edit = &Edit{Target: ID(ed.GetTarget()), Text: ed.GetText(), Delete: ed.GetDelete()}
 	}
+	if r := pbmsg.GetReply(); r != nil {
+		msg.ReplyTo = &Reply{
+			ID:      ID(r.GetId()),
+			Author:  Contact{ID: ID(r.GetAuthor().GetId()), Name: r.GetAuthor().GetName()},
+			Snippet: r.GetSnippet(),
+		}
+	}
+	msg.ThreadID = ID(pbmsg.GetThreadId())
+	if r := pbmsg.GetReaction(); r != nil {
+		msg.React = &React{Target: ID(r.GetTarget()), Emoji: r.GetEmoji(), Remove: r.GetRemove()}
+	}
+	msg.ExpiresAt = pbmsg.GetExpiresAt()
+	return msg
 }
 
 func ToChatInfo(pbmsg *pb.Request) ChatInfo {
@@ -56,6 +133,7 @@ func ToChatInfo(pbmsg *pb.Request) ChatInfo {
 		ci.Admins = append(ci.Admins, Contact{ID(v.Id),v.Name})
 	}
 	ci.Type = ChatType(pbmsg.ChatType)
+	ci.Expiry = pbmsg.GetExpiry()
 	return *ci
 }
 
@@ -64,6 +142,7 @@ func (m ChatInfo) Proto() proto.Message {
 		Name: m.Name,
 		Id: m.ID.String(),
 		ChatType: pb.CHAT_TYPES(m.Type),
+		Expiry: m.Expiry,
 	}
 	for _,v := range m.Members {
 		r.Members = append(r.Members, &pb.Contact{Name:v.Name, Id: v.ID.String()})
@@ -72,4 +151,62 @@ func (m ChatInfo) Proto() proto.Message {
 		r.Admins = append(r.Admins, &pb.Contact{Name:v.Name, Id: v.ID.String()})
 	}
 	return r
-}
\ No newline at end of file
+}
+
+func (r InviteReply) Proto() proto.Message {
+	reply := pb.Request_Declined
+	if r.Accepted {
+		reply = pb.Request_Accepted
+	}
+	return &pb.Request{
+		Id:    r.ChatID.String(),
+		Reply: reply,
+	}
+}
+
+func ToInviteReply(pbmsg *pb.Request) InviteReply {
+	return InviteReply{
+		ChatID:   ID(pbmsg.GetId()),
+		Accepted: pbmsg.GetReply() == pb.Request_Accepted,
+	}
+}
+
+func (r Receipt) Proto() proto.Message {
+	e := pb.ChatEvent_Deliverd
+	if r.Status == Seen {
+		e = pb.ChatEvent_Seen
+	}
+	return &pb.ChatEvent{
+		ChatId: r.ChatID.String(),
+		MsgId:  r.MsgID.String(),
+		Event:  e,
+	}
+}
+
+func ToReceipt(pbmsg *pb.ChatEvent) Receipt {
+	s := Delivered
+	if pbmsg.GetEvent() == pb.ChatEvent_Seen {
+		s = Seen
+	}
+	return Receipt{
+		ChatID: ID(pbmsg.GetChatId()),
+		MsgID:  ID(pbmsg.GetMsgId()),
+		Status: s,
+	}
+}
+
+func (s Signal) Proto() proto.Message {
+	return &pb.Signal{
+		ChatId: s.ChatID.String(),
+		Kind:   pb.Signal_Kind(s.Kind),
+		Ttl:    s.TTL,
+	}
+}
+
+func ToSignal(pbmsg *pb.Signal) Signal {
+	return Signal{
+		ChatID: ID(pbmsg.GetChatId()),
+		Kind:   SignalKind(pbmsg.GetKind()),
+		TTL:    pbmsg.GetTtl(),
+	}
+}
@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var ErrInvalidSignature = errors.New("invalid message signature")
+
+const messageSigDomain = "hood-chat/message/v1"
+
+// SigningBytes returns the canonical encoding of the fields covered by
+// the message signature.
+func (m Message) SigningBytes() []byte {
+	var buf bytes.Buffer
+	writeField(&buf, []byte(messageSigDomain))
+	writeField(&buf, []byte(m.ID))
+	writeField(&buf, []byte(m.ChatID))
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(m.CreatedAt))
+	writeField(&buf, ts)
+	writeField(&buf, []byte(m.Text))
+	writeField(&buf, []byte(m.Author.ID))
+	// fields added later are only encoded when set, so older signatures stay valid
+	if m.Control != nil {
+		writeField(&buf, []byte("control"))
+		writeField(&buf, []byte{byte(m.Control.Op)})
+		for _, c := range m.Control.Members {
+			writeField(&buf, []byte(c.ID))
+		}
+		writeField(&buf, []byte(m.Control.Name))
+		if m.Control.Op == SetExpiry {
+			expiry := make([]byte, 8)
+			binary.BigEndian.PutUint64(expiry, uint64(m.Control.Expiry))
+			writeField(&buf, expiry)
+		}
+	}
+	if len(m.Attachments) > 0 {
+		writeField(&buf, []byte("attachments"))
+		for _, a := range m.Attachments {
+			writeField(&buf, []byte(a.Hash))
+			writeField(&buf, []byte(a.Name))
+			writeField(&buf, []byte(a.Mime))
+			size := make([]byte, 8)
+			binary.BigEndian.PutUint64(size, uint64(a.Size))
+			writeField(&buf, size)
+		}
+	}
+	if m.Edit != nil {
+		writeField(&buf, []byte("edit"))
+		writeField(&buf, []byte(m.Edit.Target))
+		writeField(&buf, []byte(m.Edit.Text))
+		del := byte(0)
+		if m.Edit.Delete {
+			del = 1
+		}
+		writeField(&buf, []byte{del})
+	}
+	if m.ReplyTo != nil {
+		writeField(&buf, []byte("reply"))
+		writeField(&buf, []byte(m.ReplyTo.ID))
+		writeField(&buf, []byte(m.ReplyTo.Author.ID))
+		writeField(&buf, []byte(m.ReplyTo.Snippet))
+	}
+	if m.ThreadID != "" {
+		writeField(&buf, []byte("thread"))
+		writeField(&buf, []byte(m.ThreadID))
+	}
+	if m.React != nil {
+		writeField(&buf, []byte("react"))
+		writeField(&buf, []byte(m.React.Target))
+		writeField(&buf, []byte(m.React.Emoji))
+		remove := byte(0)
+		if m.React.Remove {
+			remove = 1
+		}
+		writeField(&buf, []byte{remove})
+	}
+	if m.ExpiresAt != 0 {
+		writeField(&buf, []byte("expires"))
+		at := make([]byte, 8)
+		binary.BigEndian.PutUint64(at, uint64(m.ExpiresAt))
+		writeField(&buf, at)
+	}
+	return buf.Bytes()
+}
+
+// Sign signs the message with the author's private key.
+func (m *Message) Sign(sk crypto.PrivKey) error {
+	sig, err := sk.Sign(m.SigningBytes())
+	if err != nil {
+		return err
+	}
+	m.Sig = sig
+	return nil
+}
+
+// Verify checks the signature against the public key embedded in the
+// author's peer ID.
+func (m Message) Verify() error {
+	if len(m.Sig) == 0 {
+		return ErrInvalidSignature
+	}
+	pid, err := peer.Decode(m.Author.ID.String())
+	if err != nil {
+		return err
+	}
+	pk, err := pid.ExtractPublicKey()
+	if err != nil {
+		return err
+	}
+	ok, err := pk.Verify(m.SigningBytes(), m.Sig)
+	if err != nil || !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func writeField(buf *bytes.Buffer, b []byte) {
+	l := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(l, uint64(len(b)))
+	buf.Write(l[:n])
+	buf.Write(b)
+}
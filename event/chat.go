@@ -11,9 +11,20 @@ import (
 const ChatGroup = "ChatEvent"
 // Event Names
 const Invite = "INVITE"
+// a group admin changed the group, the payload is the control message
+const GroupChange = "GROUP"
 // Event Actions
 const InviteSent       = "SENT"
 const InviteReceived   = "RECEIVED"
+const InviteAccepted = "ACCEPTED"
+const InviteDeclined = "DECLINED"
+const MembersAdded = "ADDED"
+const MembersRemoved = "REMOVED"
+const AdminsPromoted = "PROMOTED"
+const AdminsDemoted = "DEMOTED"
+const ChatRenamed = "RENAMED"
+const MemberLeft = "LEFT"
+const ExpiryChanged = "EXPIRY"
 
 type ChatEvent = IEvent[string, interface{}]
 type ChatEventGroup = IEventGroup[string, interface{}]
@@ -34,9 +45,19 @@ func NewChatEventGroup() ChatEventGroup {
 		Actions: map[string]Empty{
 			InviteSent:     {},
 			InviteReceived: {},
+			InviteAccepted: {},
+			InviteDeclined: {},
+			MembersAdded:   {},
+			MembersRemoved: {},
+			AdminsPromoted: {},
+			AdminsDemoted:  {},
+			ChatRenamed:    {},
+			MemberLeft:     {},
+			ExpiryChanged:  {},
 		},
 		Names: map[string]Empty{
-			Invite: {},
+			Invite:      {},
+			GroupChange: {},
 		},
 	}
 }
@@ -57,6 +78,14 @@ func (e *chatEG) NewEvent(name string, action string, payload interface{}) (Chat
 		break
 	case entity.ChatInfo:
 		break
+	case entity.Message:
+		break
+	case entity.ChatRequest:
+		break
+	case entity.Invitation:
+		break
+	case entity.InviteReply:
+		break
 	default:
 		return nil, ErrNotSupported
 	}
@@ -77,13 +106,31 @@ func (e *chatEG) Validate(evt ChatEvent) bool {
 	return pres
 }
 
-func EmitInvite(bus event.Bus, action string, chat entity.ChatInfo) {
+// EmitInvite emits a received ChatRequest, a delivered Invitation or an
+// InviteReply.
+func EmitInvite(bus event.Bus, action string, payload interface{}) {
 	emitter, err := bus.Emitter(new(ChatEventObj), 	eventbus.Stateful)
 	if err != nil {
 		panic("create emitter failed")
 	}
 	defer emitter.Close()
-	ev, err := ChatEG.NewEvent(Invite, action, chat)
+	ev, err := ChatEG.NewEvent(Invite, action, payload)
+	if err != nil {
+		panic(err)
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("emit event failed")
+	}
+}
+
+func EmitGroupChange(bus event.Bus, action string, msg entity.Message) {
+	emitter, err := bus.Emitter(new(ChatEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("create emitter failed")
+	}
+	defer emitter.Close()
+	ev, err := ChatEG.NewEvent(GroupChange, action, msg)
 	if err != nil {
 		panic(err)
 	}
@@ -13,6 +13,22 @@ type Empty struct{}
 const ChangeStatus = "ChangeStatus"
 const NewMessage = "NewMessage"
 
+// a message published on the topic of a group
+const NewGroupMessage = "NewGroupMessage"
+
+// a received message failed signature or author checks
+const RejectedMessage = "RejectedMessage"
+
+// a delivery or seen receipt arrived from a peer
+const Receipt = "Receipt"
+
+// a message was edited or deleted, the payload is the changed message
+const MessageEdited = "MessageEdited"
+const MessageDeleted = "MessageDeleted"
+
+// the reactions of a message changed, the payload is the message
+const MessageReacted = "MessageReacted"
+
 type MessageEvent = IEvent[entity.Status, interface{}]
 type MessageEventGroup = IEventGroup[entity.Status, interface{}]
 type MessageEventObj = EvtObject[entity.Status, interface{}]
@@ -29,15 +45,22 @@ type messagingEG struct {
 func NewMessagingEventGroup() *messagingEG {
 	return &messagingEG{
 		Actions: map[entity.Status]string{
-			entity.Seen:     "Seen",
-			entity.Sent:     "Sent",
-			entity.Pending:  "Pending",
-			entity.Received: "Received",
-			entity.Failed:   "Failed",
+			entity.Seen:      "Seen",
+			entity.Sent:      "Sent",
+			entity.Pending:   "Pending",
+			entity.Received:  "Received",
+			entity.Failed:    "Failed",
+			entity.Delivered: "Delivered",
 		},
 		Names: map[string]Empty{
-			ChangeStatus: {},
-			NewMessage:   {},
+			ChangeStatus:    {},
+			NewMessage:      {},
+			NewGroupMessage: {},
+			RejectedMessage: {},
+			Receipt:         {},
+			MessageEdited:   {},
+			MessageDeleted:  {},
+			MessageReacted:  {},
 		},
 	}
 }
@@ -57,6 +80,8 @@ func (e *messagingEG) NewEvent(name string, action entity.Status, payload interf
 		break
 	case entity.Message:
 		break
+	case entity.Receipt:
+		break
 	default:
 		return nil, ErrNotSupported
 	}
@@ -94,12 +119,71 @@ func EmitMessageChange(bus event.Bus, status entity.Status, msgID string) {
 }
 
 func EmitNewMessage(bus event.Bus, msg entity.Message) {
+	emitNewMessage(bus, NewMessage, msg)
+}
+
+// EmitGroupMessage emits a message taken from the topic of its group.
+func EmitGroupMessage(bus event.Bus, msg entity.Message) {
+	emitNewMessage(bus, NewGroupMessage, msg)
+}
+
+func emitNewMessage(bus event.Bus, name string, msg entity.Message) {
+	emitter, err := bus.Emitter(new(MessageEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("bus has problem")
+	}
+	defer emitter.Close()
+	ev, err := MessagingEG.NewEvent(name, entity.Received, msg)
+	if err != nil {
+		panic("bus has problem")
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("bus has problem")
+	}
+}
+
+// EmitMessageUpdate emits a changed message, name is MessageEdited,
+// MessageDeleted or MessageReacted.
+func EmitMessageUpdate(bus event.Bus, name string, msg entity.Message) {
+	emitter, err := bus.Emitter(new(MessageEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("bus has problem")
+	}
+	defer emitter.Close()
+	ev, err := MessagingEG.NewEvent(name, msg.Status, msg)
+	if err != nil {
+		panic("bus has problem")
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("bus has problem")
+	}
+}
+
+func EmitRejectedMessage(bus event.Bus, msg entity.Message) {
+	emitter, err := bus.Emitter(new(MessageEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("bus has problem")
+	}
+	defer emitter.Close()
+	ev, err := MessagingEG.NewEvent(RejectedMessage, entity.Failed, msg)
+	if err != nil {
+		panic("bus has problem")
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("bus has problem")
+	}
+}
+
+func EmitReceipt(bus event.Bus, r entity.Receipt) {
 	emitter, err := bus.Emitter(new(MessageEventObj), eventbus.Stateful)
 	if err != nil {
 		panic("bus has problem")
 	}
 	defer emitter.Close()
-	ev, err := MessagingEG.NewEvent(NewMessage, entity.Received, msg)
+	ev, err := MessagingEG.NewEvent(Receipt, r.Status, r)
 	if err != nil {
 		panic("bus has problem")
 	}
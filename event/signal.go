@@ -0,0 +1,102 @@
+package event
+
+import (
+	"github.com/hood-chat/core/entity"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+)
+
+// Event group name
+const SignalGroup = "SignalEvent"
+
+// Event Names
+const Typing = "TYPING"
+const Presence = "PRESENCE"
+
+// a signal arrived from a peer and is not checked yet, the checked ones
+// are emitted as Typing or Presence
+const SignalReceived = "RECEIVED"
+
+// Event Actions
+const TypingStarted = "STARTED"
+const TypingStopped = "STOPPED"
+const Online = "ONLINE"
+const Away = "AWAY"
+
+type SignalEvent = IEvent[string, entity.Signal]
+type SignalEventGroup = IEventGroup[string, entity.Signal]
+type SignalEventObj = EvtObject[string, entity.Signal]
+
+var NewSignalEvent = NewEvtObj[string, entity.Signal]
+
+var SignalEG = NewSignalEventGroup()
+
+type signalEG struct {
+	Actions map[string]string
+}
+
+func NewSignalEventGroup() SignalEventGroup {
+	return &signalEG{
+		Actions: map[string]string{
+			TypingStarted: Typing,
+			TypingStopped: Typing,
+			Online:        Presence,
+			Away:          Presence,
+		},
+	}
+}
+
+func (e *signalEG) NewEvent(name string, action string, payload entity.Signal) (SignalEvent, error) {
+	n, pres := e.Actions[action]
+	if !pres || (n != name && name != SignalReceived) {
+		return nil, ErrNotSupported
+	}
+	return NewSignalEvent(name, SignalGroup, action, payload), nil
+}
+
+func (e *signalEG) Validate(evt SignalEvent) bool {
+	if evt.GetGroup() != SignalGroup {
+		return false
+	}
+	n, pres := e.Actions[evt.GetAction()]
+	return pres && (n == evt.GetName() || evt.GetName() == SignalReceived)
+}
+
+// EmitSignal reports a typing or presence change of a peer.
+func EmitSignal(bus event.Bus, s entity.Signal) {
+	name := Typing
+	if s.Kind == entity.Online || s.Kind == entity.Away {
+		name = Presence
+	}
+	emitSignal(bus, name, s)
+}
+
+// EmitSignalReceived hands a signal from the network over to be checked.
+func EmitSignalReceived(bus event.Bus, s entity.Signal) {
+	emitSignal(bus, SignalReceived, s)
+}
+
+func emitSignal(bus event.Bus, name string, s entity.Signal) {
+	emitter, err := bus.Emitter(new(SignalEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("create emitter failed")
+	}
+	defer emitter.Close()
+	action := TypingStarted
+	switch s.Kind {
+	case entity.TypingStopped:
+		action = TypingStopped
+	case entity.Online:
+		action = Online
+	case entity.Away:
+		action = Away
+	}
+	ev, err := SignalEG.NewEvent(name, action, s)
+	if err != nil {
+		panic(err)
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("emit event failed")
+	}
+}
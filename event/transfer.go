@@ -0,0 +1,76 @@
+package event
+
+import (
+	"github.com/hood-chat/core/entity"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
+)
+
+// Event group name
+const TransferGroup = "TransferEvent"
+
+// Event Names
+const Transfer = "TRANSFER"
+
+// Event Actions
+const TransferProgress = "PROGRESS"
+const TransferCompleted = "COMPLETED"
+const TransferFailed = "FAILED"
+
+type TransferEvent = IEvent[string, entity.Transfer]
+type TransferEventGroup = IEventGroup[string, entity.Transfer]
+type TransferEventObj = EvtObject[string, entity.Transfer]
+
+var NewTransferEvent = NewEvtObj[string, entity.Transfer]
+
+var TransferEG = NewTransferEventGroup()
+
+type transferEG struct {
+	Actions map[string]Empty
+}
+
+func NewTransferEventGroup() TransferEventGroup {
+	return &transferEG{
+		Actions: map[string]Empty{
+			TransferProgress:  {},
+			TransferCompleted: {},
+			TransferFailed:    {},
+		},
+	}
+}
+
+func (e *transferEG) NewEvent(name string, action string, payload entity.Transfer) (TransferEvent, error) {
+	if name != Transfer {
+		return nil, ErrNotSupported
+	}
+	_, pres := e.Actions[action]
+	if !pres {
+		return nil, ErrNotSupported
+	}
+	return NewTransferEvent(name, TransferGroup, action, payload), nil
+}
+
+func (e *transferEG) Validate(evt TransferEvent) bool {
+	if evt.GetGroup() != TransferGroup || evt.GetName() != Transfer {
+		return false
+	}
+	_, pres := e.Actions[evt.GetAction()]
+	return pres
+}
+
+// EmitTransfer reports the progress of an attachment download.
+func EmitTransfer(bus event.Bus, action string, t entity.Transfer) {
+	emitter, err := bus.Emitter(new(TransferEventObj), eventbus.Stateful)
+	if err != nil {
+		panic("create emitter failed")
+	}
+	defer emitter.Close()
+	ev, err := TransferEG.NewEvent(Transfer, action, t)
+	if err != nil {
+		panic(err)
+	}
+	err = emitter.Emit(ev)
+	if err != nil {
+		panic("emit event failed")
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+	"github.com/hood-chat/core/protocol"
+)
+
+var ErrNotGroup = errors.New("chat is not a group")
+var ErrNotAdmin = errors.New("only admins can change the group")
+var ErrUnknownControl = errors.New("unknown group control")
+var ErrInvalidExpiry = errors.New("expiry must not be negative")
+
+var controlActions = map[entity.ControlOp]string{
+	entity.AddMembers:    event.MembersAdded,
+	entity.RemoveMembers: event.MembersRemoved,
+	entity.PromoteAdmins: event.AdminsPromoted,
+	entity.DemoteAdmins:  event.AdminsDemoted,
+	entity.RenameChat:    event.ChatRenamed,
+	entity.LeaveChat:     event.MemberLeft,
+	entity.SetExpiry:     event.ExpiryChanged,
+}
+
+func (c *Chat) AddMembers(chatID entity.ID, cons entity.ContactSlice) error {
+	return c.administer(chatID, entity.Control{Op: entity.AddMembers, Members: cons})
+}
+
+func (c *Chat) RemoveMembers(chatID entity.ID, cons entity.ContactSlice) error {
+	return c.administer(chatID, entity.Control{Op: entity.RemoveMembers, Members: cons})
+}
+
+func (c *Chat) Promote(chatID entity.ID, cons entity.ContactSlice) error {
+	return c.administer(chatID, entity.Control{Op: entity.PromoteAdmins, Members: cons})
+}
+
+func (c *Chat) Demote(chatID entity.ID, cons entity.ContactSlice) error {
+	return c.administer(chatID, entity.Control{Op: entity.DemoteAdmins, Members: cons})
+}
+
+func (c *Chat) Rename(chatID entity.ID, name string) error {
+	return c.administer(chatID, entity.Control{Op: entity.RenameChat, Name: name})
+}
+
+func (c *Chat) Leave(chatID entity.ID) error {
+	return c.administer(chatID, entity.Control{Op: entity.LeaveChat})
+}
+
+// SetExpiry makes new messages of a chat disappear after d for every
+// member, zero keeps them. Any member of a private chat can set it.
+func (c *Chat) SetExpiry(chatID entity.ID, d time.Duration) error {
+	if d < 0 {
+		return ErrInvalidExpiry
+	}
+	return c.administer(chatID, entity.Control{Op: entity.SetExpiry, Expiry: int64(d / time.Second)})
+}
+
+// administer applies a change locally and sends it to the members of the
+// chat before the change, through the topic of a group. New members get an
+// invite instead.
+func (c *Chat) administer(chatID entity.ID, ctl entity.Control) error {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	sk, err := c.Identity.PrivKey()
+	if err != nil {
+		return err
+	}
+	chat, err := c.chRepo.GetByID(chatID)
+	if err != nil {
+		return err
+	}
+	msg := entity.Message{
+		ID:        entity.ID(uuid.New().String()),
+		ChatID:    chatID,
+		CreatedAt: time.Now().UTC().Unix(),
+		Author:    *me.ToContact(),
+		ChatType:  chat.Type,
+		Control:   &ctl,
+	}
+	err = msg.Sign(sk)
+	if err != nil {
+		return err
+	}
+	updated, err := change(chat, msg)
+	if err != nil {
+		return err
+	}
+	// sent before it is saved, the change may take me off the topic
+	c.broadcast(chat, msg)
+	updated, err = c.save(updated, msg)
+	if err != nil {
+		return err
+	}
+	for _, to := range updated.Members {
+		if chat.IsMember(to.ID) {
+			continue
+		}
+		c.pms.Send(&Envelop{To: to, Message: updated, ID: updated.ID.String(), CreatedAt: time.Now().Unix(), Protocol: protocol.Invite.ID()})
+	}
+	return nil
+}
+
+// control applies a control message received from a member.
+func (c *Chat) control(msg entity.Message, viaTopic bool) error {
+	chat, err := c.chRepo.GetByID(msg.ChatID)
+	if err != nil {
+		return err
+	}
+	err = checkOrigin(chat, msg, viaTopic)
+	if err != nil {
+		return err
+	}
+	_, err = c.apply(chat, msg)
+	return err
+}
+
+// apply changes the group if the author of msg is allowed to, stores it and
+// emits a chat event.
+func (c *Chat) apply(chat entity.ChatInfo, msg entity.Message) (entity.ChatInfo, error) {
+	chat, err := change(chat, msg)
+	if err != nil {
+		return chat, err
+	}
+	return c.save(chat, msg)
+}
+
+// change returns chat with the control of msg applied if its author is
+// allowed to make it. Private chats only take an expiry.
+func change(chat entity.ChatInfo, msg entity.Message) (entity.ChatInfo, error) {
+	ctl := msg.Control
+	group := chat.Type == entity.Group
+	if !group && ctl.Op != entity.SetExpiry {
+		return chat, ErrNotGroup
+	}
+	author := msg.Author.ID
+	if !chat.IsMember(author) {
+		return chat, ErrNotMember
+	}
+	if group && ctl.Op != entity.LeaveChat && !chat.IsAdmin(author) {
+		return chat, ErrNotAdmin
+	}
+
+	switch ctl.Op {
+	case entity.AddMembers:
+		for _, m := range ctl.Members {
+			if !chat.IsMember(m.ID) {
+				chat.Members = append(chat.Members, m)
+			}
+		}
+	case entity.RemoveMembers:
+		chat.Members = withoutContacts(chat.Members, ctl.Members)
+		chat.Admins = withoutContacts(chat.Admins, ctl.Members)
+	case entity.PromoteAdmins:
+		for _, m := range ctl.Members {
+			if chat.IsMember(m.ID) && !chat.IsAdmin(m.ID) {
+				chat.Admins = append(chat.Admins, m)
+			}
+		}
+	case entity.DemoteAdmins:
+		chat.Admins = withoutContacts(chat.Admins, ctl.Members)
+	case entity.RenameChat:
+		if ctl.Name == "" {
+			return chat, errors.New("group name is empty")
+		}
+		chat.Name = ctl.Name
+	case entity.LeaveChat:
+		chat.Members = withoutContacts(chat.Members, []entity.Contact{msg.Author})
+		chat.Admins = withoutContacts(chat.Admins, []entity.Contact{msg.Author})
+	case entity.SetExpiry:
+		if ctl.Expiry < 0 {
+			return chat, ErrInvalidExpiry
+		}
+		chat.Expiry = ctl.Expiry
+	default:
+		return chat, ErrUnknownControl
+	}
+	return chat, nil
+}
+
+// save stores a changed chat, follows the topic of a group I am still in
+// and emits a chat event.
+func (c *Chat) save(chat entity.ChatInfo, msg entity.Message) (entity.ChatInfo, error) {
+	err := c.chRepo.Put(chat)
+	if err != nil {
+		return chat, err
+	}
+	me, err := c.Identity.Get()
+	if err != nil {
+		return chat, err
+	}
+	if chat.Type == entity.Group {
+		if chat.IsMember(me.ID) {
+			c.gps.Join(chat.ID, chat.Members)
+		} else {
+			c.gps.Leave(chat.ID)
+		}
+	}
+	event.EmitGroupChange(c.bus, controlActions[msg.Control.Op], msg)
+	return chat, nil
+}
+
+func withoutContacts(cs []entity.Contact, remove []entity.Contact) []entity.Contact {
+	drop := make(map[entity.ID]bool)
+	for _, c := range remove {
+		drop[c.ID] = true
+	}
+	res := make([]entity.Contact, 0, len(cs))
+	for _, c := range cs {
+		if !drop[c.ID] {
+			res = append(res, c)
+		}
+	}
+	return res
+}
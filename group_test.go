@@ -0,0 +1,175 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core"
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+	"github.com/stretchr/testify/require"
+)
+
+// accept waits for the invite to chatID and accepts it.
+func accept(t *testing.T, mr core.MessengerAPI, chatID entity.ID) {
+	require.Eventually(t, func() bool {
+		reqs, err := mr.ChatAPI().Requests(entity.Page{})
+		return err == nil && len(reqs.Items) == 1 && reqs.Items[0].Chat.ID == chatID
+	}, 10*time.Second, 50*time.Millisecond)
+	_, err := mr.ChatAPI().Accept(chatID)
+	require.NoError(t, err)
+}
+
+func TestInvites(t *testing.T) {
+	msgrs := getMockMessengers(t, 3)
+	users := make([]entity.Contact, 0)
+	for _, v := range msgrs {
+		self, err := v.IdentityAPI().Get()
+		require.NoError(t, err)
+		users = append(users, *self.ToContact())
+	}
+	chat, err := msgrs[0].ChatAPI().New(core.NewGroupChat("group", users[1:]))
+	require.NoError(t, err)
+	require.NoError(t, msgrs[0].ChatAPI().Invite(chat.ID, users[1:]))
+	invitation := func(to entity.ID, want entity.InviteStatus) {
+		require.Eventually(t, func() bool {
+			invs, err := msgrs[0].ChatAPI().Invitations(chat.ID)
+			if err != nil {
+				return false
+			}
+			for _, inv := range invs {
+				if inv.To.ID == to && inv.Status == want {
+					return true
+				}
+			}
+			return false
+		}, 10*time.Second, 50*time.Millisecond)
+	}
+	invitation(users[1].ID, entity.InviteDelivered)
+
+	// the group is not joined until the invite is accepted
+	_, err = msgrs[1].ChatAPI().ChatInfo(chat.ID)
+	require.Error(t, err)
+	accept(t, msgrs[1], chat.ID)
+	ci, err := msgrs[1].ChatAPI().ChatInfo(chat.ID)
+	require.NoError(t, err)
+	require.Equal(t, chat.Name, ci.Name)
+	invitation(users[1].ID, entity.InviteAccepted)
+
+	// the messages of the group come from its topic
+	_, err = msgrs[0].ChatAPI().Send(chat.ID, "welcome")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		msgs, err := msgrs[1].ChatAPI().Messages(chat.ID, entity.Page{})
+		return err == nil && len(msgs.Items) == 1 && msgs.Items[0].Text == "welcome"
+	}, 10*time.Second, 50*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		reqs, err := msgrs[2].ChatAPI().Requests(entity.Page{})
+		return err == nil && len(reqs.Items) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	require.NoError(t, msgrs[2].ChatAPI().Decline(chat.ID))
+	invitation(users[2].ID, entity.InviteDeclined)
+	reqs, err := msgrs[2].ChatAPI().Requests(entity.Page{})
+	require.NoError(t, err)
+	require.Empty(t, reqs.Items)
+	_, err = msgrs[2].ChatAPI().ChatInfo(chat.ID)
+	require.Error(t, err)
+
+	// only admins invite
+	require.NoError(t, msgrs[1].ChatAPI().Invite(chat.ID, users[2:]))
+	require.Eventually(t, func() bool {
+		invs, err := msgrs[1].ChatAPI().Invitations(chat.ID)
+		return err == nil && len(invs) == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	reqs, err = msgrs[2].ChatAPI().Requests(entity.Page{})
+	require.NoError(t, err)
+	require.Empty(t, reqs.Items)
+}
+
+func TestGroupAdministration(t *testing.T) {
+	msgrs := getMockMessengers(t, 3)
+	users := make([]entity.Contact, 0)
+	for _, v := range msgrs {
+		self, err := v.IdentityAPI().Get()
+		require.NoError(t, err)
+		users = append(users, *self.ToContact())
+	}
+	sub, err := msgrs[1].EventBus().Subscribe(new(event.ChatEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	chat, err := msgrs[0].ChatAPI().New(core.NewGroupChat("group", users[1:2]))
+	require.NoError(t, err)
+	require.NoError(t, msgrs[0].ChatAPI().Invite(chat.ID, users[1:2]))
+	groupOf := func(i int, check func(entity.ChatInfo) bool) {
+		require.Eventually(t, func() bool {
+			ci, err := msgrs[i].ChatAPI().ChatInfo(chat.ID)
+			return err == nil && check(ci)
+		}, 10*time.Second, 50*time.Millisecond)
+	}
+	accept(t, msgrs[1], chat.ID)
+	groupOf(1, func(ci entity.ChatInfo) bool { return true })
+
+	// only admins change the group
+	require.ErrorIs(t, msgrs[1].ChatAPI().Rename(chat.ID, "mine"), core.ErrNotAdmin)
+
+	require.NoError(t, msgrs[0].ChatAPI().AddMembers(chat.ID, users[2:]))
+	accept(t, msgrs[2], chat.ID)
+	for i := range msgrs {
+		groupOf(i, func(ci entity.ChatInfo) bool { return ci.IsMember(users[2].ID) })
+	}
+	for {
+		var evt event.ChatEventObj
+		select {
+		case e := <-sub.Out():
+			evt = e.(event.ChatEventObj)
+		case <-time.After(10 * time.Second):
+			t.Fatal("no group change event")
+		}
+		if evt.GetName() == event.GroupChange {
+			require.Equal(t, event.MembersAdded, evt.GetAction())
+			msg := evt.GetPayload().(entity.Message)
+			require.Equal(t, users[0].ID, msg.Author.ID)
+			require.Equal(t, users[2].ID, msg.Control.Members[0].ID)
+			break
+		}
+	}
+
+	require.NoError(t, msgrs[0].ChatAPI().Promote(chat.ID, users[1:2]))
+	for i := range msgrs {
+		groupOf(i, func(ci entity.ChatInfo) bool { return ci.IsAdmin(users[1].ID) })
+	}
+	require.NoError(t, msgrs[1].ChatAPI().Rename(chat.ID, "renamed"))
+	for i := range msgrs {
+		groupOf(i, func(ci entity.ChatInfo) bool { return ci.Name == "renamed" })
+	}
+	require.NoError(t, msgrs[1].ChatAPI().Demote(chat.ID, users[:1]))
+	for i := range msgrs {
+		groupOf(i, func(ci entity.ChatInfo) bool { return !ci.IsAdmin(users[0].ID) })
+	}
+
+	require.NoError(t, msgrs[2].ChatAPI().Leave(chat.ID))
+	for i := range msgrs {
+		groupOf(i, func(ci entity.ChatInfo) bool { return !ci.IsMember(users[2].ID) })
+	}
+	_, err = msgrs[2].ChatAPI().Send(chat.ID, "still here?")
+	require.ErrorIs(t, err, core.ErrNotMember)
+	left, err := msgrs[2].ChatAPI().Messages(chat.ID, entity.Page{})
+	require.NoError(t, err)
+	for _, m := range left.Items {
+		require.NotEqual(t, "still here?", m.Text)
+	}
+
+	require.NoError(t, msgrs[1].ChatAPI().RemoveMembers(chat.ID, users[:1]))
+	for i := range msgrs[:2] {
+		groupOf(i, func(ci entity.ChatInfo) bool { return !ci.IsMember(users[0].ID) })
+	}
+
+	// coming back keeps the admins I know of
+	require.NoError(t, msgrs[1].ChatAPI().AddMembers(chat.ID, users[2:]))
+	accept(t, msgrs[2], chat.ID)
+	groupOf(2, func(ci entity.ChatInfo) bool {
+		return ci.IsMember(users[2].ID) && ci.IsAdmin(users[1].ID) && !ci.IsAdmin(users[0].ID)
+	})
+}
@@ -4,13 +4,14 @@ import (
 	"time"
 
 	"github.com/hood-chat/core/entity"
+	"github.com/libp2p/go-libp2p/core/crypto"
 	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 // provide api for managing contacts
 type ContactBookAPI interface {
-	// return list of contact
-	List(skip int, limit int) (entity.ContactSlice, error)
+	// return a page of contacts
+	List(page entity.Page) (entity.ContactPage, error)
 	// return a contact by id
 	Get(id entity.ID) (entity.Contact, error)
 	// create or update contact
@@ -20,25 +21,93 @@ type ContactBookAPI interface {
 // provide api for managing identity
 type IdentityAPI interface {
 	IsLogin() bool
-	SignUp(name string) (*entity.Identity, error)
+	IsUnlocked() bool
+	// create a new identity protected by passphrase
+	SignUp(name string, passphrase string) (*entity.Identity, error)
+	// decrypt the stored identity, returns ErrWrongPassphrase on mismatch
+	Unlock(passphrase string) error
+	ChangePassphrase(old string, new string) error
+	// return the recovery phrase of the identity
+	Export(passphrase string) (string, error)
+	// recreate identity from a recovery phrase on an empty store
+	Restore(name string, mnemonic string, passphrase string) (*entity.Identity, error)
 	Get() (entity.Identity, error)
 	PeerID() (peer.ID, error)
+	PrivKey() (crypto.PrivKey, error)
 }
 
 // provide api to use chat
 type ChatAPI interface {
 	ChatInfo(id entity.ID) (entity.ChatInfo, error)
-	ChatInfos(skip int, limit int) (entity.ChatSlice, error)
+	// a page of chats with their latest message, the latest active first
+	ChatInfos(page entity.Page) (entity.ChatPage, error)
 	Join(entity.ChatInfo) error
 	Find(opt SearchChatOpt) (entity.ChatSlice, error)
 	New(opt NewChatOpt) (entity.ChatInfo, error)
 	Send(chatID entity.ID, content string) (*entity.Message, error)
+	// send again a message that failed
+	Resend(msgID entity.ID) error
+	// send a message with files attached, paths are local files
+	SendFiles(chatID entity.ID, content string, paths []string) (*entity.Message, error)
+	// local file of a downloaded attachment
+	Attachment(hash string) (string, error)
+	// download the missing attachments of a received message
+	FetchAttachments(msgID entity.ID) error
+	// change the text of my message for everyone
+	Edit(msgID entity.ID, text string) (entity.Message, error)
+	// delete a message for me, or one of mine for everyone
+	Delete(msgID entity.ID, forEveryone bool) error
+	// earlier texts of an edited message
+	History(msgID entity.ID) (entity.MessageVersionSlice, error)
+	// answer a message quoting it, optionally in its thread
+	Reply(msgID entity.ID, content string, inThread bool) (*entity.Message, error)
+	// first message of a thread and a page of its replies
+	Thread(threadID entity.ID, page entity.Page) (entity.MessagePage, error)
+	// add or take back my emoji on a message
+	React(msgID entity.ID, emoji string) (entity.Message, error)
+	Unreact(msgID entity.ID, emoji string) (entity.Message, error)
+	// tell the online members of a chat that I am typing or stopped
+	Typing(chatID entity.ID, typing bool) error
+	// tell the members of my chats that I am away or back online
+	SetAway(away bool) error
+	// whether a contact is online and when it was last seen
+	Presence(id entity.ID) entity.Presence
 	Seen(chatID entity.ID) error
 	Message(ID entity.ID) (entity.Message, error)
-	Messages(chatID entity.ID, skip int, limit int) (entity.MessageSlice, error)
+	// a page of the messages of a chat, the newest first
+	Messages(chatID entity.ID, page entity.Page) (entity.MessagePage, error)
+	// a page of the messages with all words of query, the best matches first
+	Search(query string, opt SearchMessageOpt, page entity.Page) (entity.SearchResultPage, error)
 	Invite(chID entity.ID, cons entity.ContactSlice) error
+	// invites sent for a chat and whether they were accepted
+	Invitations(chatID entity.ID) (entity.InvitationSlice, error)
+	// pending invites, a group is joined only when its invite is accepted
+	Requests(page entity.Page) (entity.ChatRequestPage, error)
+	Accept(chatID entity.ID) (entity.ChatInfo, error)
+	Decline(chatID entity.ID) error
+	// group administration, changes are sent to members as signed control
+	// messages and applied only when made by an admin
+	AddMembers(chatID entity.ID, cons entity.ContactSlice) error
+	RemoveMembers(chatID entity.ID, cons entity.ContactSlice) error
+	Promote(chatID entity.ID, cons entity.ContactSlice) error
+	Demote(chatID entity.ID, cons entity.ContactSlice) error
+	Rename(chatID entity.ID, name string) error
+	Leave(chatID entity.ID) error
+	// make new messages of a chat disappear after d for every member, zero
+	// keeps them
+	SetExpiry(chatID entity.ID, d time.Duration) error
 	updateMessageStatus(msgID entity.ID, status entity.Status) error
-	received(msg entity.Message) error
+	requeue() error
+	received(msg entity.Message, viaTopic bool) error
+	receipt(r entity.Receipt) error
+	control(msg entity.Message, viaTopic bool) error
+	edited(msg entity.Message, viaTopic bool) error
+	reacted(msg entity.Message, viaTopic bool) error
+	signaled(s entity.Signal) error
+	announce() error
+	requested(req entity.ChatRequest) error
+	invited(inv entity.Invitation) error
+	replied(r entity.InviteReply) error
 }
 
 type MessengerAPI interface {
@@ -46,7 +115,8 @@ type MessengerAPI interface {
 	ChatAPI()         ChatAPI
 	IdentityAPI()     IdentityAPI
 	EventBus()        Bus
-	Start()     
+	// start networking, identity must be unlocked
+	Start()           error
 	Stop()
 }
 
@@ -55,18 +125,31 @@ type MessengerAPI interface {
 
 type DirectService interface {
 	Send(nvlop *Envelop)
+	// send a typing or presence signal to a connected peer
+	Signal(to entity.Contact, s entity.Signal) error
 	Stop()
 }
 
 type PubSubService interface {
 	Send(PubSubEnvelop)
+	// publish a typing or presence signal to the online members of a group
+	Signal(chatID entity.ID, s entity.Signal) error
 	Stop()
 	Join(chatId entity.ID, members []entity.Contact)
+	Leave(chatId entity.ID)
 }
 
 type OutBox interface {
 	Put(key peer.ID, val Expiry)
 	Pop(key peer.ID) []Expiry
+	// forget a delivered item
+	Remove(key peer.ID, val Expiry)
+	// keep a changed item, it stays where it is queued
+	Update(key peer.ID, val Expiry)
+	// peers with queued items
+	Peers() []peer.ID
+	// whether an item timed out and is not queued again
+	Failed(key peer.ID, id string) bool
 	// expired item channel
 	C() chan Expiry
 }
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/protocol"
+	rp "github.com/hood-chat/core/repo"
+)
+
+var ErrInvalidInvite = errors.New("invite is not sent by an admin of the group")
+
+func (c *Chat) Invitations(chatID entity.ID) (entity.InvitationSlice, error) {
+	invs, _, err := c.invRepo.GetAll(entity.Page{}, rp.Filter{"ChatID": string(chatID)})
+	return invs, err
+}
+
+// Requests returns a page of the pending requests, the newest first.
+func (c *Chat) Requests(page entity.Page) (entity.ChatRequestPage, error) {
+	reqs, info, err := c.reqRepo.GetAll(page, nil)
+	return entity.ChatRequestPage{Items: reqs, PageInfo: info}, err
+}
+
+// Accept joins the group of a pending request and tells the inviter. A
+// group I left or was removed from keeps what I stored, the invite only
+// adds me back, other changes come with control messages of its admins.
+func (c *Chat) Accept(chatID entity.ID) (entity.ChatInfo, error) {
+	req, err := c.reqRepo.GetByID(chatID)
+	if err != nil {
+		return entity.ChatInfo{}, err
+	}
+	chat := req.Chat
+	if stored, err := c.chRepo.GetByID(chatID); err == nil {
+		if !stored.IsAdmin(req.From.ID) {
+			return entity.ChatInfo{}, ErrInvalidInvite
+		}
+		me, err := c.Identity.Get()
+		if err != nil {
+			return entity.ChatInfo{}, err
+		}
+		chat = stored
+		if !chat.IsMember(me.ID) {
+			chat.Members = append(chat.Members, *me.ToContact())
+		}
+	}
+	err = c.Join(chat)
+	if err != nil {
+		return entity.ChatInfo{}, err
+	}
+	err = c.reqRepo.Delete(chatID)
+	if err != nil {
+		return entity.ChatInfo{}, err
+	}
+	c.reply(req, true)
+	return chat, nil
+}
+
+// Decline drops a pending request and tells the inviter.
+func (c *Chat) Decline(chatID entity.ID) error {
+	req, err := c.reqRepo.GetByID(chatID)
+	if err != nil {
+		return err
+	}
+	err = c.reqRepo.Delete(chatID)
+	if err != nil {
+		return err
+	}
+	c.reply(req, false)
+	return nil
+}
+
+func (c *Chat) reply(req entity.ChatRequest, accepted bool) {
+	r := entity.InviteReply{ChatID: req.Chat.ID, Accepted: accepted}
+	c.pms.Send(&Envelop{To: req.From, Message: r, ID: req.Chat.ID.String() + ":reply", CreatedAt: time.Now().Unix(), Protocol: protocol.Invite.ID()})
+}
+
+// requested keeps an invite until the user answers it. Invites are only
+// accepted from admins of the group.
+func (c *Chat) requested(req entity.ChatRequest) error {
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	chat := req.Chat
+	if chat.Type != entity.Group {
+		return ErrNotGroup
+	}
+	if !chat.IsMember(me.ID) || !chat.IsMember(req.From.ID) || !chat.IsAdmin(req.From.ID) {
+		return ErrInvalidInvite
+	}
+	if joined, err := c.chRepo.GetByID(chat.ID); err == nil {
+		if joined.IsMember(me.ID) {
+			// invited again, e.g. the admin retried
+			return nil
+		}
+		// the admins I know decide whether I come back
+		if !joined.IsAdmin(req.From.ID) {
+			return ErrInvalidInvite
+		}
+	}
+	return c.reqRepo.Put(req)
+}
+
+// invited records an invite delivered to a contact.
+func (c *Chat) invited(inv entity.Invitation) error {
+	return c.invRepo.Put(inv)
+}
+
+// replied updates an invitation with the answer of the invited contact.
+func (c *Chat) replied(r entity.InviteReply) error {
+	inv, err := c.invRepo.Find(r.ChatID, r.From)
+	if err != nil {
+		return err
+	}
+	inv.Status = entity.InviteDeclined
+	if r.Accepted {
+		inv.Status = entity.InviteAccepted
+	}
+	return c.invRepo.Put(inv)
+}
@@ -0,0 +1,83 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hood-chat/core/pb"
+	pl "github.com/hood-chat/core/protocol"
+	"github.com/libp2p/go-libp2p/core/discovery"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+var ErrNoMailbox = errors.New("recipient has no mailbox")
+var ErrNoKey = errors.New("host has no private key")
+
+// Find returns up to limit mailboxes that keep envelopes for owner. Anyone
+// can advertise under the namespace of owner, only the mailboxes showing an
+// endorsement of owner are returned.
+func Find(ctx context.Context, h host.Host, disc discovery.Discovery, owner peer.ID, limit int) ([]peer.AddrInfo, error) {
+	peers, err := disc.FindPeers(ctx, Namespace(owner), discovery.Limit(limit))
+	if err != nil {
+		return nil, err
+	}
+	var res []peer.AddrInfo
+	for pi := range peers {
+		if pi.ID == owner || len(pi.Addrs) == 0 {
+			continue
+		}
+		s, err := open(ctx, h, pi, QueryID)
+		if err != nil {
+			continue
+		}
+		err = endorsement(s, owner, pi.ID)
+		if err != nil {
+			log.Debugf("mailbox %s of %s skipped: %s", pi.ID, owner, err.Error())
+			continue
+		}
+		res = append(res, pi)
+	}
+	if len(res) == 0 {
+		return nil, ErrNoMailbox
+	}
+	return res, nil
+}
+
+// Deposit leaves env with the mailbox mb.
+func Deposit(ctx context.Context, h host.Host, mb peer.AddrInfo, env *pb.MailboxEnvelope) error {
+	s, err := open(ctx, h, mb, pl.MailboxDeposit.ID())
+	if err != nil {
+		return err
+	}
+	return pl.MailboxDeposit.Send(s, env)
+}
+
+// Fetch registers h as an owner of the mailbox mb, which then delivers the
+// kept envelopes with the MailboxDeliver protocol. The mailbox is endorsed
+// with the key of h so that senders trust it.
+func Fetch(ctx context.Context, h host.Host, mb peer.AddrInfo) error {
+	sk := h.Peerstore().PrivKey(h.ID())
+	if sk == nil {
+		return ErrNoKey
+	}
+	e, err := Endorse(sk, mb.ID)
+	if err != nil {
+		return err
+	}
+	s, err := open(ctx, h, mb, pl.MailboxFetch.ID())
+	if err != nil {
+		return err
+	}
+	return pl.MailboxFetch.Send(s, &pb.MailboxFetch{Endorsement: e})
+}
+
+func open(ctx context.Context, h host.Host, mb peer.AddrInfo, id protocol.ID) (network.Stream, error) {
+	err := h.Connect(ctx, mb)
+	if err != nil {
+		return nil, err
+	}
+	return h.NewStream(network.WithUseTransient(ctx, "mailbox"), mb.ID, id)
+}
@@ -0,0 +1,105 @@
+package mailbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/hood-chat/core/pb"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-msgio/pbio"
+)
+
+var ErrNotEndorsed = errors.New("mailbox is not endorsed by the recipient")
+
+// QueryID is the protocol senders ask a mailbox for its endorsement with.
+const QueryID = protocol.ID("/chat/mailbox/query/0.0.1")
+
+// EndorsementTTL is how long an endorsement holds, owners endorse their
+// mailboxes again on every fetch.
+var EndorsementTTL = 30 * 24 * time.Hour
+
+const endorsementPrefix = "hood-chat mailbox endorsement:"
+
+// a query is dropped when not answered in time
+var queryTimeout = 10 * time.Second
+
+const maxQuerySize = 1024
+
+// Endorse signs with sk, the key of the owner, that mb keeps its envelopes.
+func Endorse(sk crypto.PrivKey, mb peer.ID) (*pb.MailboxEndorsement, error) {
+	owner, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.MarshalPublicKey(sk.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	e := &pb.MailboxEndorsement{
+		Owner:     owner.String(),
+		Mailbox:   mb.String(),
+		ExpiresAt: time.Now().Add(EndorsementTTL).Unix(),
+		Key:       key,
+	}
+	e.Sig, err = sk.Sign(endorsementBytes(e))
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Verify checks e is signed by owner for the mailbox mb and still holds.
+func Verify(e *pb.MailboxEndorsement, owner peer.ID, mb peer.ID) error {
+	if e.GetOwner() != owner.String() || e.GetMailbox() != mb.String() || len(e.GetSig()) == 0 {
+		return ErrNotEndorsed
+	}
+	if e.GetExpiresAt() < time.Now().Unix() {
+		return ErrNotEndorsed
+	}
+	pk, err := crypto.UnmarshalPublicKey(e.GetKey())
+	if err != nil || !owner.MatchesPublicKey(pk) {
+		return ErrNotEndorsed
+	}
+	ok, err := pk.Verify(endorsementBytes(e), e.GetSig())
+	if err != nil || !ok {
+		return ErrNotEndorsed
+	}
+	return nil
+}
+
+func endorsementBytes(e *pb.MailboxEndorsement) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(endorsementPrefix)
+	for _, f := range []string{e.GetOwner(), e.GetMailbox()} {
+		buf.Write(binary.AppendUvarint(nil, uint64(len(f))))
+		buf.WriteString(f)
+	}
+	buf.Write(binary.AppendVarint(nil, e.GetExpiresAt()))
+	return buf.Bytes()
+}
+
+// endorsement asks the mailbox mb for the endorsement of owner and checks it.
+func endorsement(s network.Stream, owner peer.ID, mb peer.ID) error {
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(queryTimeout))
+	wr := pbio.NewDelimitedWriter(s)
+	err := wr.WriteMsg(&pb.MailboxQuery{Owner: owner.String()})
+	if err != nil {
+		s.Reset()
+		return err
+	}
+	rd := pbio.NewDelimitedReader(s, maxQuerySize)
+	defer rd.Close()
+	e := new(pb.MailboxEndorsement)
+	err = rd.ReadMsg(e)
+	if err != nil {
+		s.Reset()
+		return err
+	}
+	return Verify(e, owner, mb)
+}
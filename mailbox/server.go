@@ -0,0 +1,266 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hood-chat/core/pb"
+	pl "github.com/hood-chat/core/protocol"
+	"github.com/hood-chat/core/store"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/discovery"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery/util"
+	"github.com/libp2p/go-msgio/pbio"
+	"google.golang.org/protobuf/proto"
+)
+
+var log = logging.Logger("msgr-core-mailbox")
+
+var ErrNotOwner = errors.New("recipient does not use this mailbox")
+var ErrMailboxFull = errors.New("mailbox of the recipient is full")
+
+// namespace a mailbox advertises itself under for each owner
+const namespace = "/hood-chat/mailbox/"
+
+// Namespace is where the mailboxes of owner are found.
+func Namespace(owner peer.ID) string {
+	return namespace + owner.String()
+}
+
+type Config struct {
+	// envelopes kept per owner
+	MaxEnvelopes uint64
+
+	// envelopes older than TTL are dropped
+	TTL time.Duration
+
+	// peers allowed to use the mailbox, anyone when empty
+	Owners []peer.ID
+}
+
+var DefaultConfig = Config{MaxEnvelopes: 1000, TTL: 30 * 24 * time.Hour}
+
+// Server keeps envelopes for its owners while they are offline and hands
+// them over when the owner fetches them. Envelopes are sealed end to end,
+// the server only sees who sent what to whom.
+type Server struct {
+	host   host.Host
+	store  *store.Store
+	disc   discovery.Discovery
+	conf   Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	// owners with a delivery in progress
+	mux        sync.Mutex
+	delivering map[peer.ID]bool
+}
+
+// NewServer serves the mailbox protocols on h, disc is used to advertise the
+// mailbox for its owners and may be nil.
+func NewServer(h host.Host, s *store.Store, disc discovery.Discovery, conf Config) *Server {
+	srv := &Server{
+		host:       h,
+		store:      s,
+		disc:       disc,
+		conf:       conf,
+		delivering: make(map[peer.ID]bool),
+	}
+	srv.ctx, srv.cancel = context.WithCancel(context.Background())
+	pl.MailboxDeposit.SetHandler(h, srv.depositHandler)
+	pl.MailboxFetch.SetHandler(h, srv.fetchHandler)
+	h.SetStreamHandler(QueryID, srv.queryHandler)
+
+	owners, err := s.MailboxOwners()
+	if err != nil {
+		log.Errorf("can not load mailbox owners: %s", err.Error())
+	}
+	for _, o := range owners {
+		id, err := peer.Decode(o.ID)
+		if err != nil {
+			continue
+		}
+		srv.advertise(id)
+	}
+	return srv
+}
+
+func (s *Server) Close() {
+	s.cancel()
+	s.host.RemoveStreamHandler(pl.MailboxDeposit.ID())
+	s.host.RemoveStreamHandler(pl.MailboxFetch.ID())
+	s.host.RemoveStreamHandler(QueryID)
+}
+
+func (s *Server) allowed(p peer.ID) bool {
+	if len(s.conf.Owners) == 0 {
+		return true
+	}
+	for _, o := range s.conf.Owners {
+		if o == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) depositHandler(from peer.ID, env *pb.MailboxEnvelope) {
+	err := s.deposit(from, env)
+	if err != nil {
+		log.Debugf("envelope %s from %s dropped: %s", env.GetId(), from, err.Error())
+	}
+}
+
+// deposit keeps env for its recipient, the sender is the peer that
+// deposited it whatever env says.
+func (s *Server) deposit(from peer.ID, env *pb.MailboxEnvelope) error {
+	to, err := peer.Decode(env.GetTo())
+	if err != nil {
+		return err
+	}
+	_, err = s.store.MailboxOwnerByID(to.String())
+	if err != nil {
+		return ErrNotOwner
+	}
+	n, err := s.store.MailboxEnvelopeCount(to.String())
+	if err != nil {
+		return err
+	}
+	if n >= s.conf.MaxEnvelopes {
+		return ErrMailboxFull
+	}
+	err = s.store.PutMailboxEnvelope(store.BHMailboxEnvelope{
+		Key:       to.String() + "/" + from.String() + "/" + env.GetId(),
+		To:        to.String(),
+		ID:        env.GetId(),
+		From:      from.String(),
+		Protocol:  env.GetProtocol(),
+		Payload:   env.GetPayload(),
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	if s.host.Network().Connectedness(to) == network.Connected {
+		go s.deliver(to)
+	}
+	return nil
+}
+
+// fetchHandler registers the peer as an owner and delivers what is kept,
+// the peer must endorse the mailbox.
+func (s *Server) fetchHandler(from peer.ID, f *pb.MailboxFetch) {
+	if !s.allowed(from) {
+		log.Debugf("fetch from %s refused", from)
+		return
+	}
+	err := Verify(f.GetEndorsement(), from, s.host.ID())
+	if err != nil {
+		log.Debugf("fetch from %s refused: %s", from, err.Error())
+		return
+	}
+	e, err := proto.Marshal(f.GetEndorsement())
+	if err != nil {
+		return
+	}
+	o, err := s.store.MailboxOwnerByID(from.String())
+	registered := err == nil
+	if !registered {
+		o = store.BHMailboxOwner{ID: from.String(), RegisteredAt: time.Now().Unix()}
+	}
+	o.Endorsement = e
+	err = s.store.PutMailboxOwner(o)
+	if err != nil {
+		log.Errorf("can not register %s: %s", from, err.Error())
+		return
+	}
+	if !registered {
+		s.advertise(from)
+	}
+	go s.deliver(from)
+}
+
+// queryHandler answers with the endorsement of the owner asked for.
+func (s *Server) queryHandler(str network.Stream) {
+	defer str.Close()
+	str.SetDeadline(time.Now().Add(queryTimeout))
+	rd := pbio.NewDelimitedReader(str, maxQuerySize)
+	defer rd.Close()
+	q := new(pb.MailboxQuery)
+	err := rd.ReadMsg(q)
+	if err != nil {
+		str.Reset()
+		return
+	}
+	e := new(pb.MailboxEndorsement)
+	o, err := s.store.MailboxOwnerByID(q.GetOwner())
+	if err == nil {
+		proto.Unmarshal(o.Endorsement, e)
+	}
+	err = pbio.NewDelimitedWriter(str).WriteMsg(e)
+	if err != nil {
+		str.Reset()
+	}
+}
+
+// deliver hands the kept envelopes to owner, one at a time. An envelope is
+// removed once the owner acknowledged it.
+func (s *Server) deliver(owner peer.ID) {
+	s.mux.Lock()
+	if s.delivering[owner] {
+		s.mux.Unlock()
+		return
+	}
+	s.delivering[owner] = true
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.delivering, owner)
+		s.mux.Unlock()
+	}()
+
+	envs, err := s.store.MailboxEnvelopes(owner.String())
+	if err != nil {
+		log.Errorf("can not load envelopes of %s: %s", owner, err.Error())
+		return
+	}
+	expired := time.Now().Add(-s.conf.TTL).Unix()
+	for _, e := range envs {
+		if e.CreatedAt < expired {
+			s.store.DeleteMailboxEnvelope(e.Key)
+			continue
+		}
+		ctx := network.WithUseTransient(s.ctx, "mailbox")
+		str, err := s.host.NewStream(ctx, owner, pl.MailboxDeliver.ID())
+		if err != nil {
+			log.Debugf("can not deliver to %s: %s", owner, err.Error())
+			return
+		}
+		err = pl.MailboxDeliver.Send(str, &pb.MailboxEnvelope{
+			Id:        e.ID,
+			From:      e.From,
+			To:        e.To,
+			Protocol:  e.Protocol,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt,
+		})
+		if err != nil {
+			return
+		}
+		err = s.store.DeleteMailboxEnvelope(e.Key)
+		if err != nil {
+			log.Errorf("can not delete envelope %s: %s", e.Key, err.Error())
+		}
+	}
+}
+
+func (s *Server) advertise(owner peer.ID) {
+	if s.disc == nil {
+		return
+	}
+	util.Advertise(s.ctx, s.disc, Namespace(owner))
+}
@@ -0,0 +1,171 @@
+package mailbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core/mailbox"
+	"github.com/hood-chat/core/pb"
+	pl "github.com/hood-chat/core/protocol"
+	"github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailbox(t *testing.T) {
+	mn, err := mocknet.FullMeshLinked(3)
+	require.NoError(t, err)
+	defer mn.Close()
+	hosts := mn.Hosts()
+	server, owner, sender := hosts[0], hosts[1], hosts[2]
+
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+	srv := mailbox.NewServer(server, s, nil, mailbox.DefaultConfig)
+	defer srv.Close()
+	mb := peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}
+
+	received := make(chan *pb.MailboxEnvelope, 10)
+	pl.MailboxDeliver.SetHandler(owner, func(from peer.ID, env *pb.MailboxEnvelope) {
+		received <- env
+	})
+	ctx := context.Background()
+	env := &pb.MailboxEnvelope{Id: "1", To: owner.ID().String(), From: "someone", Protocol: "/test", Payload: []byte("sealed")}
+
+	// only owners get a mailbox, the envelope is acknowledged but dropped
+	require.NoError(t, mailbox.Deposit(ctx, sender, mb, env))
+	time.Sleep(100 * time.Millisecond)
+	n, err := s.MailboxEnvelopeCount(owner.ID().String())
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// owners endorse the mailbox
+	str, err := sender.NewStream(ctx, server.ID(), pl.MailboxFetch.ID())
+	require.NoError(t, err)
+	require.NoError(t, pl.MailboxFetch.Send(str, &pb.MailboxFetch{}))
+	time.Sleep(100 * time.Millisecond)
+	_, err = s.MailboxOwnerByID(sender.ID().String())
+	require.Error(t, err)
+
+	require.NoError(t, mailbox.Fetch(ctx, owner, mb))
+	require.Eventually(t, func() bool {
+		_, err := s.MailboxOwnerByID(owner.ID().String())
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// kept while the owner is offline
+	require.NoError(t, mn.DisconnectPeers(server.ID(), owner.ID()))
+	require.NoError(t, mn.UnlinkPeers(server.ID(), owner.ID()))
+	require.NoError(t, mailbox.Deposit(ctx, sender, mb, env))
+	require.Eventually(t, func() bool {
+		n, err := s.MailboxEnvelopeCount(owner.ID().String())
+		return err == nil && n == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = mn.LinkPeers(server.ID(), owner.ID())
+	require.NoError(t, err)
+	require.NoError(t, mailbox.Fetch(ctx, owner, mb))
+	select {
+	case got := <-received:
+		require.Equal(t, "1", got.Id)
+		require.Equal(t, sender.ID().String(), got.From)
+		require.Equal(t, []byte("sealed"), got.Payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("envelope not delivered")
+	}
+	require.Eventually(t, func() bool {
+		n, err := s.MailboxEnvelopeCount(owner.ID().String())
+		return err == nil && n == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// delivered right away while the owner is connected
+	env.Id = "2"
+	require.NoError(t, mailbox.Deposit(ctx, sender, mb, env))
+	select {
+	case got := <-received:
+		require.Equal(t, "2", got.Id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("envelope not delivered")
+	}
+}
+
+func TestDepositOverwrite(t *testing.T) {
+	mn, err := mocknet.FullMeshLinked(4)
+	require.NoError(t, err)
+	defer mn.Close()
+	hosts := mn.Hosts()
+	server, owner, sender, other := hosts[0], hosts[1], hosts[2], hosts[3]
+
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+	srv := mailbox.NewServer(server, s, nil, mailbox.DefaultConfig)
+	defer srv.Close()
+	mb := peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()}
+
+	received := make(chan *pb.MailboxEnvelope, 10)
+	pl.MailboxDeliver.SetHandler(owner, func(from peer.ID, env *pb.MailboxEnvelope) {
+		received <- env
+	})
+	ctx := context.Background()
+	require.NoError(t, mailbox.Fetch(ctx, owner, mb))
+	require.Eventually(t, func() bool {
+		_, err := s.MailboxOwnerByID(owner.ID().String())
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, mn.DisconnectPeers(server.ID(), owner.ID()))
+	require.NoError(t, mn.UnlinkPeers(server.ID(), owner.ID()))
+
+	// another depositor reusing the id does not replace the envelope
+	env := &pb.MailboxEnvelope{Id: "1", To: owner.ID().String(), Protocol: "/test", Payload: []byte("sealed")}
+	require.NoError(t, mailbox.Deposit(ctx, sender, mb, env))
+	forged := &pb.MailboxEnvelope{Id: "1", To: owner.ID().String(), Protocol: "/test", Payload: []byte("forged")}
+	require.NoError(t, mailbox.Deposit(ctx, other, mb, forged))
+	require.Eventually(t, func() bool {
+		n, err := s.MailboxEnvelopeCount(owner.ID().String())
+		return err == nil && n == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = mn.LinkPeers(server.ID(), owner.ID())
+	require.NoError(t, err)
+	require.NoError(t, mailbox.Fetch(ctx, owner, mb))
+	got := make(map[string]string)
+	for len(got) < 2 {
+		select {
+		case e := <-received:
+			got[e.From] = string(e.Payload)
+		case <-time.After(5 * time.Second):
+			t.Fatal("envelope not delivered")
+		}
+	}
+	require.Equal(t, "sealed", got[sender.ID().String()])
+	require.Equal(t, "forged", got[other.ID().String()])
+}
+
+func TestEndorsement(t *testing.T) {
+	mn, err := mocknet.FullMeshLinked(2)
+	require.NoError(t, err)
+	defer mn.Close()
+	owner, mb := mn.Hosts()[0], mn.Hosts()[1]
+	sk := owner.Peerstore().PrivKey(owner.ID())
+
+	e, err := mailbox.Endorse(sk, mb.ID())
+	require.NoError(t, err)
+	require.NoError(t, mailbox.Verify(e, owner.ID(), mb.ID()))
+	// for another mailbox or owner
+	require.ErrorIs(t, mailbox.Verify(e, owner.ID(), owner.ID()), mailbox.ErrNotEndorsed)
+	require.ErrorIs(t, mailbox.Verify(e, mb.ID(), mb.ID()), mailbox.ErrNotEndorsed)
+	// changed after signing
+	e.ExpiresAt++
+	require.ErrorIs(t, mailbox.Verify(e, owner.ID(), mb.ID()), mailbox.ErrNotEndorsed)
+	// expired
+	ttl := mailbox.EndorsementTTL
+	mailbox.EndorsementTTL = -time.Minute
+	defer func() { mailbox.EndorsementTTL = ttl }()
+	e, err = mailbox.Endorse(sk, mb.ID())
+	require.NoError(t, err)
+	require.ErrorIs(t, mailbox.Verify(e, owner.ID(), mb.ID()), mailbox.ErrNotEndorsed)
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/mailbox"
+	"github.com/hood-chat/core/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailboxDelivery(t *testing.T) {
+	conf := outboxConfig
+	outboxConfig = Config{true, time.Second, 500 * time.Millisecond}
+	defer func() { outboxConfig = conf }()
+
+	dhtServer, err := relayBuilder{}.Create(Option{})
+	require.NoError(t, err)
+	defer dhtServer.Close()
+	profile := LocalNetwork()
+	profile.Bootstrap = []string{dhtServer.Addrs()[0].String() + "/p2p/" + dhtServer.ID().String()}
+	profile.DHT = true
+
+	// an always-on peer keeping envelopes for its owners
+	mbHost, err := DefaultRoutedHost{}.Create(Option{Network: profile})
+	require.NoError(t, err)
+	defer mbHost.Close()
+	mbStore, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer mbStore.Close()
+	srv := mailbox.NewServer(mbHost, mbStore, mbHost.(DiscoveryHost).Discovery(), mailbox.DefaultConfig)
+	defer srv.Close()
+
+	opt := Option{Network: profile, Mailboxes: []string{mbHost.Addrs()[0].String() + "/p2p/" + mbHost.ID().String()}}
+	path := t.TempDir()
+	recipient := NewMessengerAPI(path, opt, DefaultRoutedHost{})
+	me, err := recipient.IdentityAPI().SignUp("recipient", "passphrase")
+	require.NoError(t, err)
+	require.NoError(t, recipient.Start())
+	require.Eventually(t, func() bool {
+		_, err := mbStore.MailboxOwnerByID(me.ID.String())
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+	recipient.Stop()
+
+	sender := NewMessengerAPI(t.TempDir(), Option{Network: profile}, DefaultRoutedHost{})
+	_, err = sender.IdentityAPI().SignUp("sender", "passphrase")
+	require.NoError(t, err)
+	require.NoError(t, sender.Start())
+	defer sender.Stop()
+	require.NoError(t, sender.ContactBookAPI().Put(*me.ToContact()))
+	chat, err := sender.ChatAPI().New(NewPrivateChat(*me.ToContact()))
+	require.NoError(t, err)
+	msg, err := sender.ChatAPI().Send(chat.ID, "while you were away")
+	require.NoError(t, err)
+	status := func(want entity.Status) func() bool {
+		return func() bool {
+			m, err := sender.ChatAPI().Message(msg.ID)
+			return err == nil && m.Status == want
+		}
+	}
+	// kept by the mailbox instead of failing, it is not sent until the
+	// recipient acknowledges it
+	require.Eventually(t, func() bool {
+		n, err := mbStore.MailboxEnvelopeCount(me.ID.String())
+		return err == nil && n == 1
+	}, 30*time.Second, 50*time.Millisecond)
+	require.True(t, status(entity.Pending)())
+
+	// the mailbox is drained when the recipient comes back
+	recipient = NewMessengerAPI(path, opt, DefaultRoutedHost{})
+	require.NoError(t, recipient.IdentityAPI().Unlock("passphrase"))
+	require.NoError(t, recipient.Start())
+	defer recipient.Stop()
+	require.Eventually(t, func() bool {
+		m, err := recipient.ChatAPI().Message(msg.ID)
+		return err == nil && m.Text == "while you were away"
+	}, 20*time.Second, 100*time.Millisecond)
+	require.Eventually(t, status(entity.Delivered), 30*time.Second, 100*time.Millisecond)
+}
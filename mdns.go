@@ -0,0 +1,40 @@
+package core
+
+import (
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+)
+
+// service name announced on the local network
+const mdnsServiceName = "hood-chat"
+
+// LANDiscovery finds peers on the local network with mDNS and gives them to
+// the connector, contacts on the same Wi-Fi are reached without internet.
+type LANDiscovery struct {
+	h         host.Host
+	connector Connector
+	service   mdns.Service
+}
+
+func NewLANDiscovery(h host.Host, c Connector) *LANDiscovery {
+	d := &LANDiscovery{h: h, connector: c}
+	d.service = mdns.NewMdnsService(h, mdnsServiceName, d)
+	return d
+}
+
+func (d *LANDiscovery) Start() error {
+	return d.service.Start()
+}
+
+func (d *LANDiscovery) Close() error {
+	return d.service.Close()
+}
+
+func (d *LANDiscovery) HandlePeerFound(pi peer.AddrInfo) {
+	if pi.ID == d.h.ID() {
+		return
+	}
+	log.Debugf("found %s on the local network", pi.ID)
+	d.connector.Found(pi)
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLANDiscoveryFeedsConnector(t *testing.T) {
+	hosts := make([]host.Host, 0)
+	for i := 0; i < 2; i++ {
+		h, err := DefaultRoutedHost{}.Create(Option{Network: LocalNetwork()})
+		require.NoError(t, err)
+		defer h.Close()
+		hosts = append(hosts, h)
+	}
+	c := NewConnector(hosts[0])
+	// without the DHT the address of the contact is unknown
+	c.Need("test", peer.AddrInfo{ID: hosts[1].ID()})
+	time.Sleep(100 * time.Millisecond)
+	require.NotEqual(t, network.Connected, hosts[0].Network().Connectedness(hosts[1].ID()))
+
+	d := NewLANDiscovery(hosts[0], c)
+	d.HandlePeerFound(peer.AddrInfo{ID: hosts[1].ID(), Addrs: hosts[1].Addrs()})
+	require.Eventually(t, func() bool {
+		return hosts[0].Network().Connectedness(hosts[1].ID()) == network.Connected
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -1,12 +1,20 @@
 package core
 
 import (
+	"context"
+	"sync"
+	"time"
 
+	"github.com/hood-chat/core/blob"
 	"github.com/hood-chat/core/entity"
 	"github.com/hood-chat/core/event"
+	rp "github.com/hood-chat/core/repo"
+	"github.com/hood-chat/core/session"
 	"github.com/hood-chat/core/store"
 	logging "github.com/ipfs/go-log/v2"
+	lpevent "github.com/libp2p/go-libp2p/core/event"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/host/eventbus"
 )
 
@@ -14,18 +22,33 @@ var log = logging.Logger("msgr-core")
 
 var _ MessengerAPI = (*Messenger)(nil)
 
+// how often expired messages are deleted
+var SweepInterval = time.Minute
+
 type Messenger struct {
 	Host     host.Host
 	store    *store.Store
 	identity IdentityAPI
 	book     ContactBookAPI
 	pms      DirectService
-	// gps      PubSubService
+	gps      PubSubService
 	chat     ChatAPI
 	hb       Builder
 	opt      Option
 	bus      Bus
 	connector Connector
+	// nil unless the network profile enables mDNS
+	lan       *LANDiscovery
+	blobs     *blob.Store
+	blobSvc   *blob.Service
+	// closed on Stop
+	done      chan struct{}
+	// stops the outbox on Stop
+	cancel    context.CancelFunc
+	stopSweep func()
+	// bus subscriptions, wg tracks the goroutines reading them
+	subs      []lpevent.Subscription
+	wg        sync.WaitGroup
 }
 
 func NewMessengerAPI(path string, opt Option, hb Builder) MessengerAPI {
@@ -47,86 +70,269 @@ func NewMessengerAPI(path string, opt Option, hb Builder) MessengerAPI {
 		panic(err)
 	}
 	msgr.store = s
+	msgr.blobs, err = blob.NewStore(path + "/blobs")
+	if err != nil {
+		panic(err)
+	}
 	msgr.book = NewContactBook(s)
 	identity := NewIdentityAPI(s)
 	msgr.identity = identity
-	if !identity.IsLogin() {
-		return &msgr
-	}
-	msgr.Start()
 	return &msgr
 }
 
-func (m *Messenger) Start() {
-	id, err := m.identity.Get()
+// Start creates the host and messaging services. The identity has to be
+// unlocked first with IdentityAPI.Unlock or SignUp, otherwise ErrLocked is returned.
+// On error what was started is closed again, so Start may be retried.
+func (m *Messenger) Start() (err error) {
+	sk, err := m.identity.PrivKey()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	err = m.opt.SetIdentity(sk)
+	if err != nil {
+		return err
 	}
-	m.opt.SetIdentity(&id)
 	h, err := m.hb.Create(m.opt)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	m.Host = h
+	defer func() {
+		if err != nil {
+			m.stop()
+		}
+	}()
 	m.connector = NewConnector(h)
-	gpCh := make(chan string)
-	m.pms = NewDirectMessaging(h, m.bus, m.connector,make(chan*Envelop))
-	// m.gps = NewGPService(context.Background(),h,m.IdentityAPI(),m.bus,gpCh,m.connector)
-	m.chat = NewChatAPI(m.store, m.book, m.pms, m.identity)
+	if m.opt.Network.MDNS {
+		m.lan = NewLANDiscovery(h, m.connector)
+		err = m.lan.Start()
+		if err != nil {
+			log.Warnf("local network discovery failed: %s", err.Error())
+			m.lan = nil
+		}
+	}
+	sessions, err := session.NewManager(m.store, sk)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	outbox, err := NewStoreOutBox(ctx, outboxConfig, m.store)
+	if err != nil {
+		return err
+	}
+	mailboxes, err := m.opt.MailboxInfos()
+	if err != nil {
+		return err
+	}
+	m.pms = NewDirectMessaging(h, m.bus, m.connector, make(chan *Envelop), sessions, outbox, mailboxes, m.opt.Retry)
+	m.gps, err = NewGPService(context.Background(), h, m.bus, m.connector)
+	if err != nil {
+		return err
+	}
+	m.blobSvc = blob.NewService(h, m.blobs, m.canFetchBlob, m.transferProgress)
+	m.chat = NewChatAPI(m.store, m.book, m.pms, m.gps, m.identity, m.bus, m.blobSvc)
 
-	chats,err := m.chat.ChatInfos(0,0)
+	chats,err := m.chat.ChatInfos(entity.Page{})
 	if err != nil {
-		panic("cant load groups chats")
+		return err
 	}
-	for _, c := range chats {
+	for _, c := range chats.Items {
 		if c.Type == entity.Group {
-			gpCh <- c.ID.String()
+			m.gps.Join(c.ID, c.Members)
 		}
 	}
 
 	msgSub, err := m.bus.Subscribe(new(event.MessageEventObj))
 	if err != nil {
-		panic(err)
+		return err
+	}
+	// messages stored but never handed to the outbox, e.g. the app was killed
+	err = m.chat.requeue()
+	if err != nil {
+		return err
 	}
+	m.subs = append(m.subs, msgSub)
+	m.wg.Add(1)
 	go func() {
-		defer msgSub.Close()
+		defer m.wg.Done()
 		for e := range msgSub.Out() {
 			evt := e.(event.MessageEventObj)
 			switch msg := e.(event.MessageEventObj).GetPayload().(type) {
 			case entity.ID:
-				if evt.GetAction() == entity.Sent || evt.GetAction() == entity.Failed {
+				switch evt.GetAction() {
+				case entity.Pending, entity.Sent, entity.Failed:
 					m.chat.updateMessageStatus(msg, evt.GetAction())
 				}
 			case entity.Message:
-				m.messageHandler(msg)
+				switch evt.GetName() {
+				case event.NewMessage:
+					m.messageHandler(msg, false)
+				case event.NewGroupMessage:
+					m.messageHandler(msg, true)
+				}
+			case entity.Receipt:
+				err := m.chat.receipt(msg)
+				if err != nil {
+					log.Warnf("receipt for %s from %s ignored: %s", msg.MsgID, msg.From, err.Error())
+				}
+			}
+		}
+	}()
+
+	signalSub, err := m.bus.Subscribe(new(event.SignalEventObj))
+	if err != nil {
+		return err
+	}
+	m.subs = append(m.subs, signalSub)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for e := range signalSub.Out() {
+			evt := e.(event.SignalEventObj)
+			if evt.GetName() != event.SignalReceived {
+				continue
+			}
+			err := m.chat.signaled(evt.GetPayload())
+			if err != nil {
+				log.Debugf("signal from %s ignored: %s", evt.GetPayload().From, err.Error())
 			}
 		}
 	}()
+	m.done = make(chan struct{})
+	done := m.done
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.announcePresence(done)
+	}()
+	m.stopSweep = rp.NewMessageRepo(m.store).Sweep(SweepInterval, m.expired)
 
 	chatSub, err := m.bus.Subscribe(new(event.ChatEventObj))
 	if err != nil {
-		panic(err)
+		return err
 	}
+	m.subs = append(m.subs, chatSub)
+	m.wg.Add(1)
 	go func() {
-		defer chatSub.Close()
+		defer m.wg.Done()
 		for e := range chatSub.Out() {
 			evt := e.(event.ChatEventObj)
-			switch evt.GetAction() {
-			case event.InviteReceived:
-				m.ChatAPI().Join(evt.GetPayload().(entity.ChatInfo))
-			case event.InviteSent:
-				// change Chat info so it keep track of chat request's
-				break;
+			if evt.GetName() != event.Invite {
+				continue
+			}
+			var err error
+			switch p := evt.GetPayload().(type) {
+			case entity.ChatRequest:
+				err = m.chat.requested(p)
+			case entity.Invitation:
+				err = m.chat.invited(p)
+			case entity.InviteReply:
+				err = m.chat.replied(p)
+			}
+			if err != nil {
+				log.Warnf("invite %s ignored: %s", evt.GetAction(), err.Error())
 			}
 		}
 	}()
+	return nil
 }
 
-func (m *Messenger) messageHandler(msg entity.Message) {
-	err := m.chat.received(msg)
-	if err != nil {
+// messageHandler takes a message of a peer, viaTopic tells it came from the
+// topic of a group rather than straight from its author.
+func (m *Messenger) messageHandler(msg entity.Message, viaTopic bool) {
+	if msg.Control != nil {
+		err := m.chat.control(msg, viaTopic)
+		if err != nil {
+			log.Warnf("group change %s from %s ignored: %s", msg.ID, msg.Author.ID, err.Error())
+		}
 		return
 	}
+	if msg.Edit != nil {
+		err := m.chat.edited(msg, viaTopic)
+		if err != nil {
+			log.Warnf("edit %s from %s ignored: %s", msg.ID, msg.Author.ID, err.Error())
+		}
+		return
+	}
+	if msg.React != nil {
+		err := m.chat.reacted(msg, viaTopic)
+		if err != nil {
+			log.Warnf("reaction %s from %s ignored: %s", msg.ID, msg.Author.ID, err.Error())
+		}
+		return
+	}
+	err := m.chat.received(msg, viaTopic)
+	if err != nil {
+		log.Warnf("message %s from %s ignored: %s", msg.ID, msg.Author.ID, err.Error())
+	}
+}
+
+// announcePresence keeps telling the members of my chats that I am online.
+func (m *Messenger) announcePresence(done <-chan struct{}) {
+	t := time.NewTicker(PresenceTTL / 2)
+	defer t.Stop()
+	for {
+		err := m.chat.announce()
+		if err != nil {
+			log.Debugf("presence not sent: %s", err.Error())
+		}
+		select {
+		case <-t.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// expired drops the attachments no message refers to anymore and tells
+// the app about the deleted messages.
+func (m *Messenger) expired(msgs entity.MessageSlice) {
+	for _, msg := range msgs {
+		for _, a := range msg.Attachments {
+			chats, err := m.store.BlobChats(a.Hash)
+			if err != nil || len(chats) > 0 {
+				continue
+			}
+			err = m.blobs.Remove(a.Hash)
+			if err != nil {
+				log.Warnf("attachment %s of expired %s not removed: %s", a.Hash, msg.ID, err.Error())
+			}
+		}
+		msg.Text = ""
+		msg.Attachments = nil
+		msg.Deleted = true
+		event.EmitMessageUpdate(m.bus, event.MessageDeleted, msg)
+	}
+}
+
+// canFetchBlob lets members of a chat fetch the blobs attached to its
+// messages.
+func (m *Messenger) canFetchBlob(p peer.ID, hash string) bool {
+	chats, err := m.store.BlobChats(hash)
+	if err != nil {
+		return false
+	}
+	for _, id := range chats {
+		chat, err := m.chat.ChatInfo(entity.ID(id))
+		if err == nil && chat.IsMember(entity.ID(p.String())) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Messenger) transferProgress(p blob.Progress) {
+	t := entity.Transfer{Hash: p.Hash, Received: p.Received, Size: p.Size}
+	switch {
+	case p.Err != nil:
+		t.Error = p.Err.Error()
+		event.EmitTransfer(m.bus, event.TransferFailed, t)
+	case p.Done:
+		event.EmitTransfer(m.bus, event.TransferCompleted, t)
+	default:
+		event.EmitTransfer(m.bus, event.TransferProgress, t)
+	}
 }
 
 func (m *Messenger) ContactBookAPI() ContactBookAPI {
@@ -146,7 +352,51 @@ func (m *Messenger) EventBus() Bus {
 }
 
 func (m *Messenger) Stop() {
-	m.store.Close()
-	m.pms.Stop()
-	m.Host.Close()
+	m.stop()
+	if m.store != nil {
+		m.store.Close()
+	}
+}
+
+// stop closes the host and the services running on it, the store stays
+// open.
+func (m *Messenger) stop() {
+	if m.done != nil {
+		close(m.done)
+		m.done = nil
+	}
+	if m.stopSweep != nil {
+		m.stopSweep()
+		m.stopSweep = nil
+	}
+	if m.lan != nil {
+		m.lan.Close()
+		m.lan = nil
+	}
+	// every service is stopped before the store they write to is closed
+	if m.gps != nil {
+		m.gps.Stop()
+		m.gps = nil
+	}
+	if m.pms != nil {
+		m.pms.Stop()
+		m.pms = nil
+	}
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	if m.blobSvc != nil {
+		m.blobSvc.Close()
+		m.blobSvc = nil
+	}
+	for _, sub := range m.subs {
+		sub.Close()
+	}
+	m.subs = nil
+	m.wg.Wait()
+	if m.Host != nil {
+		m.Host.Close()
+		m.Host = nil
+	}
 }
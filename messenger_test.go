@@ -1,16 +1,103 @@
 package core_test
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hood-chat/core"
 	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
 	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p/core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
 
+// mockBuilder creates messenger hosts on a mock network.
+type mockBuilder struct {
+	mn       mocknet.Mocknet
+	identity core.IdentityAPI
+}
+
+func (b *mockBuilder) Create(opt core.Option) (host.Host, error) {
+	sk, err := b.identity.PrivKey()
+	if err != nil {
+		return nil, err
+	}
+	n := len(b.mn.Hosts()) + 1
+	return b.mn.AddPeer(sk, ma.StringCast(fmt.Sprintf("/ip4/10.0.0.%d/tcp/4001", n)))
+}
+
+// closingHost tells whether the messenger closed it.
+type closingHost struct {
+	host.Host
+	closed bool
+}
+
+func (h *closingHost) Close() error {
+	h.closed = true
+	return h.Host.Close()
+}
+
+// closingBuilder keeps the hosts it creates.
+type closingBuilder struct {
+	*mockBuilder
+	hosts []*closingHost
+}
+
+func (b *closingBuilder) Create(opt core.Option) (host.Host, error) {
+	h, err := b.mockBuilder.Create(opt)
+	if err != nil {
+		return nil, err
+	}
+	ch := &closingHost{Host: h}
+	b.hosts = append(b.hosts, ch)
+	return ch, nil
+}
+
+// getMockMessengers starts n connected messengers on a mock network.
+func getMockMessengers(t *testing.T, n int) []core.MessengerAPI {
+	mn := mocknet.New()
+	messengers := make([]core.MessengerAPI, 0)
+	for i := 0; i < n; i++ {
+		name := "h" + fmt.Sprint(i)
+		b := &mockBuilder{mn: mn}
+		mr := core.NewMessengerAPI(t.TempDir()+"/"+name, core.Option{}, b)
+		b.identity = mr.IdentityAPI()
+		_, err := mr.IdentityAPI().SignUp(name, "")
+		require.NoError(t, err)
+		require.NoError(t, mr.Start())
+		t.Cleanup(mr.Stop)
+		messengers = append(messengers, mr)
+	}
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+	return messengers
+}
+
+func TestStartFailure(t *testing.T) {
+	b := &closingBuilder{mockBuilder: &mockBuilder{mn: mocknet.New()}}
+	mr := core.NewMessengerAPI(t.TempDir()+"/h", core.Option{Mailboxes: []string{"not an address"}}, b)
+	t.Cleanup(mr.Stop)
+	b.identity = mr.IdentityAPI()
+	_, err := mr.IdentityAPI().SignUp("h", "")
+	require.NoError(t, err)
+
+	// a failed start closes its host, another start creates a new one
+	require.Error(t, mr.Start())
+	require.Error(t, mr.Start())
+	require.Len(t, b.hosts, 2)
+	for _, h := range b.hosts {
+		require.True(t, h.closed)
+	}
+}
+
 func TestMessenger(t *testing.T) {
 	t.Log("start test")
 	err := logging.SetLogLevel("msgr-core", "DEBUG")
@@ -21,15 +108,16 @@ func TestMessenger(t *testing.T) {
 	opt2 := core.DefaultOption()
 	mr1 := core.NewMessengerAPI(t.TempDir()+"/h1", opt1, core.DefaultRoutedHost{})
 	t.Log("somthing wrong")
-	_, err = mr1.IdentityAPI().SignUp("h1")
+	_, err = mr1.IdentityAPI().SignUp("h1", "h1 passphrase")
+	require.NoError(t, err)
+	err = mr1.Start()
 	require.NoError(t, err)
-	mr1.Start()
 	t.Log("messenger 1 created")
 	_, err = mr1.IdentityAPI().Get()
 	require.NoError(t, err)
 	mr2 := core.NewMessengerAPI(t.TempDir()+"/h2", opt2, core.DefaultRoutedHost{})
-	_, err = mr2.IdentityAPI().SignUp("h2")
-	mr2.Start()
+	_, err = mr2.IdentityAPI().SignUp("h2", "h2 passphrase")
+	require.NoError(t, mr2.Start())
 	require.NoError(t, err)
 	user2, err := mr2.IdentityAPI().Get()
 	require.NoError(t, err)
@@ -57,18 +145,18 @@ func TestMessenger(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, chat1.ID, chat2.ID)
 
-	msgs, err := mr2.ChatAPI().Messages(chat1.ID, 0, 20)
+	msgs, err := mr2.ChatAPI().Messages(chat1.ID, entity.Page{Limit: 20})
 	require.NoError(t, err)
 	t.Logf("list of messages \n %v", msgs)
 
 	// Test Event hand event handler
 	time.Sleep(10 * time.Second)
-	msgs, err = mr1.ChatAPI().Messages(chat1.ID, 0, 20)
-	require.Equal(t, 2, len(msgs))
-	require.Equal(t, int(chat2.Unread), len(msgs))
+	msgs, err = mr1.ChatAPI().Messages(chat1.ID, entity.Page{Limit: 20})
+	require.Equal(t, 2, len(msgs.Items))
+	require.Equal(t, int(chat2.Unread), len(msgs.Items))
 	require.NoError(t, err)
-	for _, val := range msgs {
-		require.Equal(t, val.Status, entity.Sent)
+	for _, val := range msgs.Items {
+		require.Equal(t, val.Status, entity.Delivered)
 	}
 
 	// Test Seen
@@ -89,12 +177,12 @@ func getMessengers(t *testing.T, n int) []core.MessengerAPI {
 		name := "h" + fmt.Sprint(i)
 		opt := core.DefaultOption()
 		mr := core.NewMessengerAPI(t.TempDir()+"/"+name, opt, core.DefaultRoutedHost{})
-		_, err := mr.IdentityAPI().SignUp(name)
+		_, err := mr.IdentityAPI().SignUp(name, name+" passphrase")
 		if err != nil {
 			panic("cant create host")
 		}
 		require.NoError(t, err)
-		mr.Start()
+		require.NoError(t, mr.Start())
 		messengers = append(messengers, mr)
 	}
 	return messengers
@@ -132,3 +220,419 @@ func TestGroup(t *testing.T) {
 	}
 
 }
+
+func TestReceipts(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	require.NoError(t, mr1.ContactBookAPI().Put(*user2.ToContact()))
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+
+	msg, err := mr1.ChatAPI().Send(chat.ID, "hello")
+	require.NoError(t, err)
+	status := func(want entity.Status) func() bool {
+		return func() bool {
+			m, err := mr1.ChatAPI().Message(msg.ID)
+			return err == nil && m.Status == want
+		}
+	}
+	require.Eventually(t, status(entity.Delivered), 10*time.Second, 50*time.Millisecond)
+
+	received, err := mr2.ChatAPI().Message(msg.ID)
+	require.NoError(t, err)
+	require.Equal(t, entity.Received, received.Status)
+	// only failed messages are resent
+	require.ErrorIs(t, mr1.ChatAPI().Resend(msg.ID), core.ErrNotResendable)
+
+	require.NoError(t, mr2.ChatAPI().Seen(chat.ID))
+	require.Eventually(t, status(entity.Seen), 10*time.Second, 50*time.Millisecond)
+}
+
+func TestAttachments(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	sub, err := mr2.EventBus().Subscribe(new(event.TransferEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	path := filepath.Join(t.TempDir(), "photo.png")
+	data := bytes.Repeat([]byte("pixel"), 50000)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	msg, err := mr1.ChatAPI().SendFiles(chat.ID, "look", []string{path})
+	require.NoError(t, err)
+	require.Len(t, msg.Attachments, 1)
+	att := msg.Attachments[0]
+	require.Equal(t, "photo.png", att.Name)
+	require.Equal(t, "image/png", att.Mime)
+	require.Equal(t, int64(len(data)), att.Size)
+
+	for done := false; !done; {
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.TransferEventObj)
+			require.NotEqual(t, event.TransferFailed, evt.GetAction(), evt.GetPayload().Error)
+			done = evt.GetAction() == event.TransferCompleted
+		case <-time.After(10 * time.Second):
+			t.Fatal("attachment not fetched")
+		}
+	}
+	received, err := mr2.ChatAPI().Message(msg.ID)
+	require.NoError(t, err)
+	require.Equal(t, msg.Attachments, received.Attachments)
+	local, err := mr2.ChatAPI().Attachment(att.Hash)
+	require.NoError(t, err)
+	got, err := os.ReadFile(local)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+}
+
+func TestEditAndDelete(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	msg, err := mr1.ChatAPI().Send(chat.ID, "helo")
+	require.NoError(t, err)
+	received := func(check func(m entity.Message) bool) func() bool {
+		return func() bool {
+			m, err := mr2.ChatAPI().Message(msg.ID)
+			return err == nil && check(m)
+		}
+	}
+	require.Eventually(t, received(func(entity.Message) bool { return true }), 10*time.Second, 50*time.Millisecond)
+	require.Eventually(t, func() bool {
+		m, err := mr1.ChatAPI().Message(msg.ID)
+		return err == nil && m.Status == entity.Delivered
+	}, 10*time.Second, 50*time.Millisecond)
+
+	edited, err := mr1.ChatAPI().Edit(msg.ID, "hello")
+	require.NoError(t, err)
+	require.Equal(t, "hello", edited.Text)
+	require.NotZero(t, edited.EditedAt)
+	require.Eventually(t, received(func(m entity.Message) bool { return m.Text == "hello" }), 10*time.Second, 50*time.Millisecond)
+	history, err := mr2.ChatAPI().History(msg.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "helo", history[0].Text)
+
+	// only the author changes a message
+	_, err = mr2.ChatAPI().Edit(msg.ID, "hacked")
+	require.ErrorIs(t, err, core.ErrNotAuthor)
+	require.ErrorIs(t, mr2.ChatAPI().Delete(msg.ID, true), core.ErrNotAuthor)
+
+	require.NoError(t, mr1.ChatAPI().Delete(msg.ID, true))
+	require.Eventually(t, received(func(m entity.Message) bool { return m.Deleted && m.Text == "" }), 10*time.Second, 50*time.Millisecond)
+	history, err = mr2.ChatAPI().History(msg.ID)
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	require.NoError(t, mr2.ChatAPI().Delete(msg.ID, false))
+	_, err = mr2.ChatAPI().Message(msg.ID)
+	require.Error(t, err)
+}
+
+func TestReplies(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	long := strings.Repeat("lunch ", 30)
+	root, err := mr1.ChatAPI().Send(chat.ID, long)
+	require.NoError(t, err)
+	receivedBy := func(mr core.MessengerAPI, id entity.ID) entity.Message {
+		var m entity.Message
+		require.Eventually(t, func() bool {
+			m, err = mr.ChatAPI().Message(id)
+			return err == nil
+		}, 10*time.Second, 50*time.Millisecond)
+		return m
+	}
+	receivedBy(mr2, root.ID)
+
+	// a quote travels with the reply
+	plain, err := mr1.ChatAPI().Reply(root.ID, "anyone?", false)
+	require.NoError(t, err)
+	got := receivedBy(mr2, plain.ID)
+	require.Equal(t, root.ID, got.ReplyTo.ID)
+	require.Equal(t, root.Author.ID, got.ReplyTo.Author.ID)
+	require.Equal(t, []rune(long)[:100], []rune(got.ReplyTo.Snippet)[:100])
+	require.Empty(t, got.ThreadID)
+
+	first, err := mr2.ChatAPI().Reply(root.ID, "me", true)
+	require.NoError(t, err)
+	require.Equal(t, root.ID, first.ThreadID)
+	receivedBy(mr1, first.ID)
+	// message times have a one second resolution
+	time.Sleep(time.Second)
+	// answering inside a thread stays in it
+	second, err := mr1.ChatAPI().Reply(first.ID, "great", false)
+	require.NoError(t, err)
+	require.Equal(t, root.ID, second.ThreadID)
+	receivedBy(mr2, second.ID)
+
+	thread, err := mr2.ChatAPI().Thread(root.ID, entity.Page{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, thread.Items, 3)
+	require.Equal(t, root.ID, thread.Items[0].ID)
+	require.Equal(t, first.ID, thread.Items[1].ID)
+	require.Equal(t, second.ID, thread.Items[2].ID)
+	require.False(t, thread.More)
+	// the first message only heads the page that starts the thread
+	thread, err = mr2.ChatAPI().Thread(root.ID, entity.Page{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, thread.Items, 2)
+	require.True(t, thread.More)
+	thread, err = mr2.ChatAPI().Thread(root.ID, entity.Page{After: thread.End, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, thread.Items, 1)
+	require.Equal(t, second.ID, thread.Items[0].ID)
+	require.False(t, thread.More)
+}
+
+func TestReactions(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user1, err := mr1.IdentityAPI().Get()
+	require.NoError(t, err)
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	msg, err := mr1.ChatAPI().Send(chat.ID, "pizza tonight?")
+	require.NoError(t, err)
+	reactions := func(mr core.MessengerAPI) []entity.Reaction {
+		m, err := mr.ChatAPI().Message(msg.ID)
+		if err != nil {
+			return nil
+		}
+		return m.Reactions
+	}
+	require.Eventually(t, func() bool {
+		_, err := mr2.ChatAPI().Message(msg.ID)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+	sub, err := mr1.EventBus().Subscribe(new(event.MessageEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	_, err = mr1.ChatAPI().React(msg.ID, "")
+	require.ErrorIs(t, err, core.ErrInvalidEmoji)
+
+	// reacting twice counts once
+	_, err = mr2.ChatAPI().React(msg.ID, "👍")
+	require.NoError(t, err)
+	got, err := mr2.ChatAPI().React(msg.ID, "👍")
+	require.NoError(t, err)
+	require.Equal(t, []entity.Reaction{{Emoji: "👍", Count: 1, Authors: []entity.ID{user2.ID}}}, got.Reactions)
+	for done := false; !done; {
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.MessageEventObj)
+			done = evt.GetName() == event.MessageReacted
+		case <-time.After(10 * time.Second):
+			t.Fatal("reaction not received")
+		}
+	}
+	require.Equal(t, got.Reactions, reactions(mr1))
+
+	_, err = mr1.ChatAPI().React(msg.ID, "👍")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		r := reactions(mr2)
+		return len(r) == 1 && r[0].Count == 2 && r[0].Authors[1] == user1.ID
+	}, 10*time.Second, 50*time.Millisecond)
+
+	got, err = mr2.ChatAPI().Unreact(msg.ID, "👍")
+	require.NoError(t, err)
+	require.Equal(t, []entity.ID{user1.ID}, got.Reactions[0].Authors)
+	require.Eventually(t, func() bool {
+		r := reactions(mr1)
+		return len(r) == 1 && r[0].Count == 1
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestTypingAndPresence(t *testing.T) {
+	ttl := core.TypingTTL
+	core.TypingTTL = time.Second
+	defer func() { core.TypingTTL = ttl }()
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user1, err := mr1.IdentityAPI().Get()
+	require.NoError(t, err)
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	// the chat is known to the other peer from its first message
+	msg, err := mr1.ChatAPI().Send(chat.ID, "hi")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := mr2.ChatAPI().Message(msg.ID)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+
+	sub, err := mr2.EventBus().Subscribe(new(event.SignalEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+	next := func(name string) event.SignalEventObj {
+		for {
+			select {
+			case e := <-sub.Out():
+				evt := e.(event.SignalEventObj)
+				if evt.GetName() == name {
+					return evt
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("no %s event", name)
+			}
+		}
+	}
+
+	require.NoError(t, mr1.ChatAPI().Typing(chat.ID, true))
+	evt := next(event.Typing)
+	require.Equal(t, event.TypingStarted, evt.GetAction())
+	require.Equal(t, user1.ID, evt.GetPayload().From)
+	require.Equal(t, chat.ID, evt.GetPayload().ChatID)
+	// typing ends by itself when it is not sent again
+	evt = next(event.Typing)
+	require.Equal(t, event.TypingStopped, evt.GetAction())
+
+	// typing tells it was there
+	p := mr2.ChatAPI().Presence(user1.ID)
+	require.False(t, p.Online)
+	require.NotZero(t, p.LastSeen)
+	require.NoError(t, mr1.ChatAPI().SetAway(false))
+	evt = next(event.Presence)
+	require.Equal(t, event.Online, evt.GetAction())
+	p = mr2.ChatAPI().Presence(user1.ID)
+	require.True(t, p.Online)
+	require.NotZero(t, p.LastSeen)
+
+	require.NoError(t, mr1.ChatAPI().SetAway(true))
+	evt = next(event.Presence)
+	require.Equal(t, event.Away, evt.GetAction())
+	require.False(t, mr2.ChatAPI().Presence(user1.ID).Online)
+}
+
+func TestDisappearingMessages(t *testing.T) {
+	interval := core.SweepInterval
+	core.SweepInterval = 100 * time.Millisecond
+	defer func() { core.SweepInterval = interval }()
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	kept, err := mr1.ChatAPI().Send(chat.ID, "hi")
+	require.NoError(t, err)
+	require.Zero(t, kept.ExpiresAt)
+	require.Eventually(t, func() bool {
+		_, err := mr2.ChatAPI().Message(kept.ID)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+
+	require.ErrorIs(t, mr1.ChatAPI().SetExpiry(chat.ID, -time.Second), core.ErrInvalidExpiry)
+	require.NoError(t, mr1.ChatAPI().SetExpiry(chat.ID, 3*time.Second))
+	require.Eventually(t, func() bool {
+		c, err := mr2.ChatAPI().ChatInfo(chat.ID)
+		return err == nil && c.Expiry == 3
+	}, 10*time.Second, 50*time.Millisecond)
+
+	sub, err := mr2.EventBus().Subscribe(new(event.MessageEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+	path := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(path, []byte("burn after reading"), 0600))
+	msg, err := mr1.ChatAPI().SendFiles(chat.ID, "secret", []string{path})
+	require.NoError(t, err)
+	require.Equal(t, msg.CreatedAt+3, msg.ExpiresAt)
+	hash := msg.Attachments[0].Hash
+	require.Eventually(t, func() bool {
+		_, err := mr2.ChatAPI().Attachment(hash)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond)
+	got, err := mr2.ChatAPI().Message(msg.ID)
+	require.NoError(t, err)
+	require.Equal(t, msg.ExpiresAt, got.ExpiresAt)
+
+	for done := false; !done; {
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.MessageEventObj)
+			if evt.GetName() == event.MessageDeleted {
+				require.Equal(t, msg.ID, evt.GetPayload().(entity.Message).ID)
+				done = true
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("message did not expire")
+		}
+	}
+	for _, mr := range msgrs {
+		// the sender sweeps on its own
+		require.Eventually(t, func() bool {
+			_, err := mr.ChatAPI().Message(msg.ID)
+			_, fileErr := mr.ChatAPI().Attachment(hash)
+			return err != nil && fileErr != nil
+		}, 5*time.Second, 50*time.Millisecond)
+		_, err = mr.ChatAPI().Message(kept.ID)
+		require.NoError(t, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	msgrs := getMockMessengers(t, 2)
+	mr1, mr2 := msgrs[0], msgrs[1]
+	user1, err := mr1.IdentityAPI().Get()
+	require.NoError(t, err)
+	user2, err := mr2.IdentityAPI().Get()
+	require.NoError(t, err)
+	chat, err := mr1.ChatAPI().New(core.NewPrivateChat(*user2.ToContact()))
+	require.NoError(t, err)
+	long := strings.Repeat("nothing to see here ", 5) + "the Deploy key is in the vault " + strings.Repeat("and more filler ", 10)
+	for _, text := range []string{"deploy tonight?", long} {
+		_, err := mr1.ChatAPI().Send(chat.ID, text)
+		require.NoError(t, err)
+	}
+	var res entity.SearchResultPage
+	require.Eventually(t, func() bool {
+		res, err = mr2.ChatAPI().Search("DEPLOY", core.SearchMessageOpt{}, entity.Page{})
+		return err == nil && len(res.Items) == 2
+	}, 10*time.Second, 50*time.Millisecond)
+	first, err := mr2.ChatAPI().Search("DEPLOY", core.SearchMessageOpt{}, entity.Page{Limit: 1})
+	require.NoError(t, err)
+	require.True(t, first.More)
+	require.Equal(t, res.Items[0].Message.ID, first.Items[0].Message.ID)
+	next, err := mr2.ChatAPI().Search("DEPLOY", core.SearchMessageOpt{}, entity.Page{After: first.End})
+	require.NoError(t, err)
+	require.False(t, next.More)
+	require.Len(t, next.Items, 1)
+	require.Equal(t, res.Items[1].Message.ID, next.Items[0].Message.ID)
+
+	res, err = mr2.ChatAPI().Search("deploy key", core.SearchMessageOpt{ChatID: &chat.ID, Authors: []entity.ID{user1.ID}}, entity.Page{})
+	require.NoError(t, err)
+	require.Len(t, res.Items, 1)
+	got := res.Items[0]
+	require.True(t, strings.HasPrefix(got.Snippet, "…"))
+	require.True(t, strings.HasSuffix(got.Snippet, "…"))
+	require.Len(t, got.Highlights, 2)
+	snippet := []rune(got.Snippet)
+	require.Equal(t, "Deploy", string(snippet[got.Highlights[0].Start:got.Highlights[0].End]))
+	require.Equal(t, "key", string(snippet[got.Highlights[1].Start:got.Highlights[1].End]))
+
+	res, err = mr2.ChatAPI().Search("deploy", core.SearchMessageOpt{Authors: []entity.ID{user2.ID}}, entity.Page{})
+	require.NoError(t, err)
+	require.Empty(t, res.Items)
+	_, err = mr2.ChatAPI().Search("?!", core.SearchMessageOpt{}, entity.Page{})
+	require.ErrorIs(t, err, core.ErrEmptyQuery)
+}
@@ -3,16 +3,17 @@ package core
 import (
 	"context"
 
-	"github.com/hood-chat/core/entity"
 	ds "github.com/ipfs/go-datastore"
 	dsync "github.com/ipfs/go-datastore/sync"
 	libp2p "github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/discovery"
 	host "github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 
 	dht "github.com/libp2p/go-libp2p-kad-dht"
-	"github.com/libp2p/go-libp2p/p2p/host/autorelay"
+	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	rh "github.com/libp2p/go-libp2p/p2p/host/routed"
 
 	"github.com/ipfs/kubo/core/bootstrap"
@@ -39,56 +40,168 @@ type Builder interface {
 	Create(opt Option) (host.Host, error)
 }
 
-// func(Option)
-
-type Option struct {
-	LpOpt []libp2p.Option
-	ID    peer.ID
+// Network is the profile of the p2p network a messenger joins, it picks the
+// infrastructure (bootstrap peers and relays) and which features are used.
+type Network struct {
+	// peers used to join the DHT
+	Bootstrap []string
+	// circuit relay v2 relays that keep us reachable behind NAT
+	Relays []string
+	// libp2p default addresses when empty
+	ListenAddrs []string
+	// connection manager watermarks, libp2p default when zero
+	ConnLow  int
+	ConnHigh int
+	// route peers with the DHT, otherwise peers must be connected directly
+	DHT bool
+	// reserve slots on Relays and advertise them in the DHT
+	AutoRelay    bool
+	HolePunching bool
+	// find peers on the local network with mDNS
+	MDNS bool
 }
 
-func (opt *Option) SetIdentity(identity *entity.Identity) error {
-	sk, err := identity.DecodePrivateKey("passphrase todo!")
-	if err != nil {
-		return err
+// DefaultNetwork uses the public bootstrap peers and relays.
+func DefaultNetwork() Network {
+	return Network{
+		Bootstrap:    BootstrapNodes,
+		Relays:       StaticRelays,
+		ConnLow:      10,
+		ConnHigh:     100,
+		DHT:          true,
+		AutoRelay:    true,
+		HolePunching: true,
+		MDNS:         true,
 	}
-	opt.LpOpt = append(opt.LpOpt, libp2p.Identity(sk))
-	opt.ID = peer.ID(identity.ID)
-	return nil
 }
 
-func DefaultOption() Option {
-	bts, err := ParseBootstrapPeers(StaticRelays)
-	if err != nil {
-		panic(err)
+// LocalNetwork listens on loopback only and needs no infrastructure.
+func LocalNetwork() Network {
+	return Network{
+		ListenAddrs: []string{"/ip4/127.0.0.1/tcp/0"},
+		ConnLow:     10,
+		ConnHigh:    100,
 	}
-	con, err := connmgr.NewConnManager(10, 100)
-	if err != nil {
-		panic(err)
+}
+
+// LANNetwork finds peers on the local network only, e.g. a lab without
+// internet.
+func LANNetwork() Network {
+	return Network{
+		ConnLow:  10,
+		ConnHigh: 100,
+		MDNS:     true,
 	}
+}
+
+func (n Network) RelayInfos() ([]peer.AddrInfo, error) {
+	return ParseBootstrapPeers(n.Relays)
+}
 
+// LibP2POptions returns the libp2p options of the profile.
+func (n Network) LibP2POptions() ([]libp2p.Option, error) {
 	opt := []libp2p.Option{
 		libp2p.DefaultTransports,
 		libp2p.DefaultSecurity,
-		libp2p.DefaultListenAddrs,
-		libp2p.ConnectionManager(con),
-		libp2p.EnableAutoRelay(autorelay.WithCircuitV1Support(),autorelay.WithStaticRelays(bts)),
 		libp2p.EnableNATService(),
-		libp2p.EnableHolePunching(),
 	}
+	if len(n.ListenAddrs) > 0 {
+		opt = append(opt, libp2p.ListenAddrStrings(n.ListenAddrs...))
+	} else {
+		opt = append(opt, libp2p.DefaultListenAddrs)
+	}
+	if n.ConnHigh > 0 {
+		con, err := connmgr.NewConnManager(n.ConnLow, n.ConnHigh)
+		if err != nil {
+			return nil, err
+		}
+		opt = append(opt, libp2p.ConnectionManager(con))
+	}
+	if n.HolePunching {
+		opt = append(opt, libp2p.EnableHolePunching())
+	}
+	return opt, nil
+}
+
+// func(Option)
+
+type Option struct {
+	// extra libp2p options, added after the ones of Network
+	LpOpt   []libp2p.Option
+	ID      peer.ID
+	Network Network
+	// always-on peers that keep envelopes for us while we are offline
+	Mailboxes []string
+	// how failed direct envelops are sent again
+	Retry RetryPolicy
+}
+
+func (opt Option) MailboxInfos() ([]peer.AddrInfo, error) {
+	return ParseBootstrapPeers(opt.Mailboxes)
+}
+
+func (opt *Option) SetIdentity(sk crypto.PrivKey) error {
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	opt.LpOpt = append(opt.LpOpt, libp2p.Identity(sk))
+	opt.ID = id
+	return nil
+}
+
+func DefaultOption() Option {
 	return Option{
-		LpOpt: opt,
-		ID:    "",
+		ID:      "",
+		Network: DefaultNetwork(),
+		Retry:   DefaultRetryPolicy(),
 	}
 }
 
+// DiscoveryHost is a host that finds peers by namespace in the DHT.
+type DiscoveryHost interface {
+	host.Host
+	// nil when the host does not use the DHT
+	Discovery() discovery.Discovery
+}
+
+type dhtHost struct {
+	host.Host
+	disc discovery.Discovery
+}
+
+func (h *dhtHost) Discovery() discovery.Discovery {
+	return h.disc
+}
+
 type DefaultRoutedHost struct {
 }
 
 func (b DefaultRoutedHost) Create(opt Option) (host.Host, error) {
-	basicHost, err := libp2p.New(opt.LpOpt...)
+	lpOpt, err := opt.Network.LibP2POptions()
 	if err != nil {
 		return nil, err
 	}
+	var relays *Relays
+	if opt.Network.AutoRelay && len(opt.Network.Relays) > 0 {
+		infos, err := opt.Network.RelayInfos()
+		if err != nil {
+			return nil, err
+		}
+		relays = NewRelays(infos)
+		lpOpt = append(lpOpt, libp2p.AddrsFactory(relays.AddrsFactory))
+	}
+	basicHost, err := libp2p.New(append(lpOpt, opt.LpOpt...)...)
+	if err != nil {
+		return nil, err
+	}
+	if !opt.Network.DHT {
+		if relays != nil {
+			relays.Start(basicHost, nil)
+			return &relayHost{basicHost, relays}, nil
+		}
+		return basicHost, nil
+	}
 
 	// Construct a datastore (needed by the DHT). This is just a simple, in-memory thread-safe datastore.
 	dstore := dsync.MutexWrap(ds.NewMapDatastore())
@@ -96,7 +209,7 @@ func (b DefaultRoutedHost) Create(opt Option) (host.Host, error) {
 	// Make the DHT
 	kDht := dht.NewDHT(context.Background(), basicHost, dstore)
 
-	bts, err := ParseBootstrapPeers(append(BootstrapNodes, StaticRelays...))
+	bts, err := ParseBootstrapPeers(append(append([]string{}, opt.Network.Bootstrap...), opt.Network.Relays...))
 	if err != nil {
 		return nil, err
 	}
@@ -106,14 +219,19 @@ func (b DefaultRoutedHost) Create(opt Option) (host.Host, error) {
 	// connect to the chosen ipfs nodes
 	_, err = bootstrap.Bootstrap(basicHost.ID(), basicHost, kDht, btconf)
 	if err != nil {
-		log.Error("bootstrap failed. ", err)
-		return nil, err
+		// keep going, peers may still be found on the local network
+		log.Warnf("bootstrap failed: %s", err.Error())
 	}
 	// Make the routed host
 	routedHost := rh.Wrap(basicHost, kDht)
+	disc := drouting.NewRoutingDiscovery(kDht)
 
 	log.Infof("core bootstrapped and ready on:", routedHost.Addrs())
-	return routedHost, nil
+	if relays != nil {
+		relays.Start(routedHost, disc)
+		return &relayHost{routedHost, relays}, nil
+	}
+	return &dhtHost{routedHost, disc}, nil
 }
 
 func ParseBootstrapPeers(addrs []string) ([]peer.AddrInfo, error) {
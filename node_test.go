@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalNetwork(t *testing.T) {
+	hosts := make([]host.Host, 0)
+	for i := 0; i < 2; i++ {
+		h, err := DefaultRoutedHost{}.Create(Option{Network: LocalNetwork()})
+		require.NoError(t, err)
+		defer h.Close()
+		require.NotEmpty(t, h.Addrs())
+		for _, a := range h.Addrs() {
+			require.True(t, manet.IsIPLoopback(a), a.String())
+		}
+		hosts = append(hosts, h)
+	}
+	err := hosts[0].Connect(context.Background(), peer.AddrInfo{ID: hosts[1].ID(), Addrs: hosts[1].Addrs()})
+	require.NoError(t, err)
+	require.Equal(t, network.Connected, hosts[0].Network().Connectedness(hosts[1].ID()))
+}
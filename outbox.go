@@ -2,10 +2,12 @@ package core
 
 import (
 	"context"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
+	bf "github.com/libp2p/go-libp2p/p2p/discovery/backoff"
 )
 
 type Data map[peer.ID]map[string]Expiry
@@ -31,6 +33,27 @@ type Config struct {
 	Interval time.Duration
 }
 
+// outboxConfig is used for direct messages, failed envelops are kept
+// and sent once the peer connects.
+var outboxConfig = Config{true, 5 * time.Minute, 1 * time.Minute}
+
+// RetryPolicy queues failed envelops again, attempts are spaced by Backoff
+// until the envelop is older than MaxAge.
+type RetryPolicy struct {
+	// nil disables automatic retries
+	Backoff bf.BackoffFactory
+
+	// envelops older than MaxAge stay failed until resent by the user
+	MaxAge time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Backoff: bf.NewExponentialBackoff(30*time.Second, 30*time.Minute, bf.FullJitter, time.Second, 2, 0, rand.NewSource(time.Now().UnixNano())),
+		MaxAge:  7 * 24 * time.Hour,
+	}
+}
+
 type outbox struct {
 	conf    Config
 	mux     sync.Mutex
@@ -59,6 +82,13 @@ func NewOutBox(ctx context.Context, conf Config) OutBox {
 func (o *outbox) Put(key peer.ID, val Expiry) {
 	o.mux.Lock()
 	defer o.mux.Unlock()
+	// queued again, it is no longer failed
+	if m, ok := o.passive[key]; ok {
+		delete(m, val.id())
+		if len(m) == 0 {
+			delete(o.passive, key)
+		}
+	}
 	o.active.Add(key, val)
 	o.adjustTicker()
 }
@@ -76,15 +106,63 @@ func (o *outbox) Pop(key peer.ID) []Expiry {
 		}
 	}
 
-	o.mux.Unlock()
 	o.adjustTicker()
+	o.mux.Unlock()
 	return msgs
 }
 
+func (o *outbox) Remove(key peer.ID, val Expiry) {
+	o.mux.Lock()
+	for _, d := range []Data{o.active, o.passive} {
+		if m, ok := d[key]; ok {
+			delete(m, val.id())
+			if len(m) == 0 {
+				delete(d, key)
+			}
+		}
+	}
+	o.adjustTicker()
+	o.mux.Unlock()
+}
+
+func (o *outbox) Update(key peer.ID, val Expiry) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	for _, d := range []Data{o.active, o.passive} {
+		if _, ok := d[key][val.id()]; ok {
+			d[key][val.id()] = val
+		}
+	}
+}
+
+func (o *outbox) Peers() []peer.ID {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	seen := make(map[peer.ID]bool)
+	var peers []peer.ID
+	for _, d := range []Data{o.active, o.passive} {
+		for k := range d {
+			if !seen[k] {
+				seen[k] = true
+				peers = append(peers, k)
+			}
+		}
+	}
+	return peers
+}
+
+func (o *outbox) Failed(key peer.ID, id string) bool {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	_, ok := o.passive[key][id]
+	return ok
+}
+
 func (o *outbox) C() chan Expiry {
 	return o.failed
 }
 
+// adjustTicker runs the ticker while envelops are active, o.mux is held.
 func (o *outbox) adjustTicker() {
 	if o.paused && len(o.active) > 0 {
 		o.paused = false
@@ -101,18 +179,27 @@ func (o *outbox) background(ctx context.Context) {
 	for {
 		select {
 		case t := <-o.ticker.C:
+			var expired []Expiry
 			o.mux.Lock()
 			for k, v := range o.active {
 				for sk, sm := range v {
 					if t.After(sm.createdAt().Add(o.conf.Timeout)) {
 						delete(v, sk)
-						o.failed <- sm
 						o.passive.Add(k, sm)
+						expired = append(expired, sm)
 					}
 				}
 			}
-			o.mux.Unlock()
 			o.adjustTicker()
+			o.mux.Unlock()
+			// sent without the lock, the reader may call back into the outbox
+			for _, sm := range expired {
+				select {
+				case o.failed <- sm:
+				case <-ctx.Done():
+					return
+				}
+			}
 		case e := <-ctx.Done():
 			log.Error("context error broke sender", e)
 			return
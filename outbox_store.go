@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/pb"
+	"github.com/hood-chat/core/protocol"
+	st "github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/peer"
+	pl "github.com/libp2p/go-libp2p/core/protocol"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrUnknownEnvelop = errors.New("envelop protocol is not supported")
+
+// storeOutbox keeps a copy of every queued envelop in the store until it is
+// delivered, so a killed app resumes delivery on the next start.
+type storeOutbox struct {
+	OutBox
+	store *st.Store
+}
+
+// NewStoreOutBox creates an outbox backed by the store, envelops left by a
+// previous run are queued again.
+func NewStoreOutBox(ctx context.Context, conf Config, s *st.Store) (OutBox, error) {
+	o := &storeOutbox{NewOutBox(ctx, conf), s}
+	bhs, err := s.Envelops()
+	if err != nil {
+		return nil, err
+	}
+	for _, bh := range bhs {
+		e, err := envelopFromStore(bh)
+		if err != nil {
+			log.Warnf("drop stored envelop %s: %s", bh.Key, err.Error())
+			s.DeleteEnvelop(bh.Key)
+			continue
+		}
+		o.OutBox.Put(e.PeerID(), e)
+	}
+	return o, nil
+}
+
+func (o *storeOutbox) Put(key peer.ID, val Expiry) {
+	if e, ok := val.(*Envelop); ok {
+		bh, err := envelopToStore(key, e)
+		if err == nil {
+			err = o.store.PutEnvelop(bh)
+		}
+		if err != nil {
+			log.Errorf("can not persist envelop %s: %s", e.ID, err.Error())
+		}
+	}
+	o.OutBox.Put(key, val)
+}
+
+func (o *storeOutbox) Update(key peer.ID, val Expiry) {
+	if e, ok := val.(*Envelop); ok {
+		bh, err := envelopToStore(key, e)
+		if err == nil {
+			err = o.store.PutEnvelop(bh)
+		}
+		if err != nil {
+			log.Errorf("can not persist envelop %s: %s", e.ID, err.Error())
+		}
+	}
+	o.OutBox.Update(key, val)
+}
+
+func (o *storeOutbox) Remove(key peer.ID, val Expiry) {
+	err := o.store.DeleteEnvelop(envelopKey(key, val.id()))
+	if err != nil {
+		log.Errorf("can not delete envelop %s: %s", val.id(), err.Error())
+	}
+	o.OutBox.Remove(key, val)
+}
+
+func envelopKey(p peer.ID, id string) string {
+	return p.String() + "/" + id
+}
+
+func envelopToStore(p peer.ID, e *Envelop) (st.BHEnvelop, error) {
+	b, err := proto.Marshal(e.Message.Proto())
+	if err != nil {
+		return st.BHEnvelop{}, err
+	}
+	var sealed []byte
+	if e.Sealed != nil {
+		sealed, err = proto.Marshal(e.Sealed)
+		if err != nil {
+			return st.BHEnvelop{}, err
+		}
+	}
+	return st.BHEnvelop{
+		Key:       envelopKey(p, e.ID),
+		ID:        e.ID,
+		To:        st.BHContact{ID: e.To.ID.String(), Name: e.To.Name},
+		Protocol:  string(e.Protocol),
+		Payload:   b,
+		Sealed:    sealed,
+		CreatedAt: e.CreatedAt,
+	}, nil
+}
+
+func envelopFromStore(bh st.BHEnvelop) (*Envelop, error) {
+	e := &Envelop{
+		To:        entity.Contact{ID: entity.ID(bh.To.ID), Name: bh.To.Name},
+		ID:        bh.ID,
+		CreatedAt: bh.CreatedAt,
+		Protocol:  pl.ID(bh.Protocol),
+	}
+	switch e.Protocol {
+	case protocol.Message.ID():
+		m := new(pb.Message)
+		if err := proto.Unmarshal(bh.Payload, m); err != nil {
+			return nil, err
+		}
+		e.Message = entity.ToMessage(m)
+		if len(bh.Sealed) > 0 {
+			e.Sealed = new(pb.SecureMessage)
+			if err := proto.Unmarshal(bh.Sealed, e.Sealed); err != nil {
+				return nil, err
+			}
+		}
+	case protocol.Invite.ID():
+		m := new(pb.Request)
+		if err := proto.Unmarshal(bh.Payload, m); err != nil {
+			return nil, err
+		}
+		if m.GetReply() != pb.Request_None {
+			e.Message = entity.ToInviteReply(m)
+		} else {
+			e.Message = entity.ToChatInfo(m)
+		}
+	case protocol.ChatEvent.ID():
+		m := new(pb.ChatEvent)
+		if err := proto.Unmarshal(bh.Payload, m); err != nil {
+			return nil, err
+		}
+		e.Message = entity.ToReceipt(m)
+	default:
+		return nil, ErrUnknownEnvelop
+	}
+	return e, nil
+}
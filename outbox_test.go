@@ -5,7 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/protocol"
+	st "github.com/hood-chat/core/store"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
 )
 
 type mockExpiry struct {
@@ -97,8 +101,86 @@ func TestOutboxTimeOut(t *testing.T) {
 	failedMsgs := outbox.C()
 	<-failedMsgs
 	time.Sleep(3 * time.Second)
+	require.True(t, outbox.Failed(key, val3.id()))
+
+	// queued again, the failed copy is dropped
+	val3.C = time.Now()
+	outbox.Put(key, val3)
+	require.False(t, outbox.Failed(key, val3.id()))
 	msgs = outbox.Pop(key)
 	if len(msgs) != 1 {
 		t.Errorf("Expected 1 messages, got %d, %s", len(msgs), msgs)
 	}
 }
+
+func TestStoreOutboxReload(t *testing.T) {
+	s, err := st.NewStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+	iden, err := entity.CreateIdentity("to", "")
+	require.NoError(t, err)
+	to := *iden.ToContact()
+	key, err := to.PeerID()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o, err := NewStoreOutBox(ctx, outboxConfig, s)
+	require.NoError(t, err)
+	msg := entity.Message{ID: "m1", ChatID: "c1", CreatedAt: time.Now().Unix(), Text: "hello", Author: to}
+	env, err := NewMessageEnvelop(to, msg)
+	require.NoError(t, err)
+	o.Put(key, env)
+	receipt, err := NewReceiptEnvelop(to, entity.Receipt{ChatID: "c1", MsgID: "m0", Status: entity.Seen})
+	require.NoError(t, err)
+	o.Put(key, receipt)
+	chat := entity.NewPrivateChat(to, to)
+	o.Put(key, &Envelop{To: to, Message: chat, ID: chat.ID.String(), CreatedAt: time.Now().Unix(), Protocol: protocol.Invite.ID()})
+
+	// popped envelops are kept in the store until they are removed
+	require.Len(t, o.Pop(key), 3)
+	o.Remove(key, receipt)
+
+	// app restarted
+	o, err = NewStoreOutBox(ctx, outboxConfig, s)
+	require.NoError(t, err)
+	require.Equal(t, []peer.ID{key}, o.Peers())
+	msgs := o.Pop(key)
+	require.Len(t, msgs, 2)
+	for _, m := range msgs {
+		e := m.(*Envelop)
+		require.Equal(t, to, e.To)
+		switch e.Protocol {
+		case protocol.Message.ID():
+			require.Equal(t, env.ID, e.ID)
+			require.Equal(t, msg.Text, e.Message.(entity.Message).Text)
+		case protocol.Invite.ID():
+			require.Equal(t, chat.ID, e.Message.(entity.ChatInfo).ID)
+		default:
+			t.Fatalf("unexpected envelop %v", e)
+		}
+	}
+}
+
+func TestOutboxUnreadFailed(t *testing.T) {
+	key := peer.ID("testKey")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outbox := NewOutBox(ctx, Config{Keep: true, Timeout: time.Millisecond, Interval: 10 * time.Millisecond})
+	outbox.Put(key, &mockExpiry{"val1", time.Now().Add(-time.Second)})
+	time.Sleep(100 * time.Millisecond)
+
+	// nobody reads the failed envelop, the outbox is still usable
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		outbox.Put(key, &mockExpiry{"val2", time.Now()})
+		require.True(t, outbox.Failed(key, "val1"))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("outbox is locked by an unread failed envelop")
+	}
+	require.Equal(t, "val1", (<-outbox.C()).id())
+}
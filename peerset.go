@@ -132,6 +132,13 @@ func (p *PeerSet) Force(id peer.ID) {
 	}
 }
 
+func (p *PeerSet) Has(id peer.ID) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	_, ok := p.set[id]
+	return ok
+}
+
 func (p *PeerSet) Empty() bool {
 	p.mux.Lock()
 	defer p.mux.Unlock()
@@ -12,7 +12,7 @@ import (
 func getPeers(n int) []peer.AddrInfo {
 	peers := make([]peer.AddrInfo, 0)
 	for i := 0; i < n; i++ {
-		p, _ := entity.CreateIdentity("asd")
+		p, _ := entity.CreateIdentity("asd", "")
 		a, _ := p.ToContact().AdderInfo()
 		peers = append(peers, *a)
 	}
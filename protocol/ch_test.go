@@ -9,6 +9,7 @@ import (
 
 	"github.com/hood-chat/core/pb"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
 	bhost "github.com/libp2p/go-libp2p/p2p/host/blank"
 	swarmt "github.com/libp2p/go-libp2p/p2p/net/swarm/testing"
 )
@@ -32,7 +33,7 @@ func TestChatEvent(t *testing.T) {
 	
 	hosts := getNetHosts(t, 5)
 	for _, h := range hosts {
-		p.SetHandler(h,func(m *pb.ChatEvent) {
+		p.SetHandler(h,func(from peer.ID, m *pb.ChatEvent) {
 			t.Logf("message received %v", m)
 		})
 	}
@@ -9,6 +9,7 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
 	pl "github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-msgio/pbio"
 )
@@ -29,7 +30,8 @@ type Meta struct {
 var log = logging.Logger("chat-protocols")
 
 type Protocol[M any] interface {
-	SetHandler(h host.Host, cb func(m M))
+	// SetHandler registers cb for incoming messages, from is the remote peer of the stream
+	SetHandler(h host.Host, cb func(from peer.ID, m M))
 	Send(s network.Stream, pbmsg M) error
 	GetMeta() *Meta
 	ID() pl.ID
@@ -46,13 +48,15 @@ func (p protocol[M]) ID() pl.ID {
 	return p.meta.ID
 }
 
-func (p protocol[M]) SetHandler(h host.Host, cb func(m M)) {
+func (p protocol[M]) SetHandler(h host.Host, cb func(from peer.ID, m M)) {
 	h.SetStreamHandler(p.meta.ID, func(s network.Stream) {
+		from := s.Conn().RemotePeer()
 		msg, err := p.read(s)
 		if err != nil {
 			log.Error("failed to read the message: ", err)
+			return
 		}
-		cb(msg)
+		cb(from, msg)
 	})
 }
 
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/hood-chat/core/pb"
+	pl "github.com/libp2p/go-libp2p/core/protocol"
+)
+
+type MailboxEnvelopeProtocol = Protocol[*pb.MailboxEnvelope]
+type mailboxEnvelopeProtocol = protocol[*pb.MailboxEnvelope]
+
+type MailboxFetchProtocol = Protocol[*pb.MailboxFetch]
+type mailboxFetchProtocol = protocol[*pb.MailboxFetch]
+
+func newMailboxEnvelopeProtocol(id string, service string) MailboxEnvelopeProtocol {
+	meta := new(Meta)
+
+	meta.MessageTimeout = time.Second * 60
+	meta.ID = pl.ID(id)
+	meta.ServiceName = service
+	meta.MaxMsgSize = 16 * 1024 // secure message plus envelope
+	meta.StreamTimeout = time.Minute
+	meta.ConnectTimeout = 30 * time.Second
+	return mailboxEnvelopeProtocol{
+		meta: *meta,
+		m: func() *pb.MailboxEnvelope {
+			return &pb.MailboxEnvelope{}
+		},
+	}
+}
+
+// NewMailboxDepositProtocol carries envelopes from a sender to a mailbox of
+// the recipient.
+func NewMailboxDepositProtocol() MailboxEnvelopeProtocol {
+	return newMailboxEnvelopeProtocol("/chat/mailbox/deposit/0.0.1", "chat.mailbox")
+}
+
+// NewMailboxDeliverProtocol carries kept envelopes from a mailbox to their
+// recipient.
+func NewMailboxDeliverProtocol() MailboxEnvelopeProtocol {
+	return newMailboxEnvelopeProtocol("/chat/mailbox/deliver/0.0.1", "chat.mailbox")
+}
+
+func NewMailboxFetchProtocol() MailboxFetchProtocol {
+	meta := new(Meta)
+
+	meta.MessageTimeout = time.Second * 60
+	meta.ID = "/chat/mailbox/fetch/0.0.1"
+	meta.ServiceName = "chat.mailbox"
+	meta.MaxMsgSize = 1024
+	meta.StreamTimeout = time.Minute
+	meta.ConnectTimeout = 30 * time.Second
+	return mailboxFetchProtocol{
+		meta: *meta,
+		m: func() *pb.MailboxFetch {
+			return &pb.MailboxFetch{}
+		},
+	}
+}
+
+var MailboxDeposit = NewMailboxDepositProtocol()
+var MailboxDeliver = NewMailboxDeliverProtocol()
+var MailboxFetch = NewMailboxFetchProtocol()
@@ -0,0 +1,29 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/hood-chat/core/pb"
+)
+
+type SecureMessageProtocol = Protocol[*pb.SecureMessage]
+type secureMessageProtocol = protocol[*pb.SecureMessage]
+
+// NewSecureMessageProtocol carries end to end encrypted messages.
+func NewSecureMessageProtocol() SecureMessageProtocol {
+	meta := new(Meta)
+	meta.MessageTimeout = time.Second * 60
+	meta.ID = "/chat/secure_message/0.0.1"
+	meta.ServiceName = "chat.secure_message"
+	meta.MaxMsgSize = 12 * 1024 // message plus ratchet header
+	meta.StreamTimeout = time.Minute
+	meta.ConnectTimeout = 30 * time.Second
+	return secureMessageProtocol{
+		meta: *meta,
+		m: func() *pb.SecureMessage {
+			return &pb.SecureMessage{}
+		},
+	}
+}
+
+var SecureMessage = NewSecureMessageProtocol()
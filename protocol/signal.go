@@ -0,0 +1,31 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/hood-chat/core/pb"
+)
+
+type SignalProtocol = Protocol[*pb.Signal]
+type signalProtocol = protocol[*pb.Signal]
+
+// NewSignalProtocol carries typing and presence of private chats, a signal
+// that can not be sent right away is dropped.
+func NewSignalProtocol() SignalProtocol {
+	meta := new(Meta)
+
+	meta.MessageTimeout = 5 * time.Second
+	meta.ID = "/chat/signal/0.0.1"
+	meta.ServiceName = "chat.signal"
+	meta.MaxMsgSize = 1024
+	meta.StreamTimeout = 10 * time.Second
+	meta.ConnectTimeout = 5 * time.Second
+	return signalProtocol{
+		meta: *meta,
+		m: func() *pb.Signal {
+			return &pb.Signal{}
+		},
+	}
+}
+
+var Signal = NewSignalProtocol()
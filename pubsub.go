@@ -1,14 +1,14 @@
 package core
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/hood-chat/core/entity"
 	"github.com/hood-chat/core/event"
 	"github.com/hood-chat/core/pb"
-	ma "github.com/multiformats/go-multiaddr"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/libp2p/go-libp2p/core/host"
@@ -19,53 +19,80 @@ import (
 
 var _ PubSubService = (*GPService)(nil)
 
-const (
-	JOINED = "joined"
+var ErrNotMember = errors.New("message author is not a member of the group")
+var ErrWrongChat = errors.New("message does not belong to the group")
+
+// group topics only carry signed messages of members, peers forwarding
+// anything else are scored down and eventually ignored.
+var (
+	groupScoreParams = &pubsub.PeerScoreParams{
+		SkipAtomicValidation: true,
+		Topics:               make(map[string]*pubsub.TopicScoreParams),
+		AppSpecificScore:     func(peer.ID) float64 { return 0 },
+		DecayInterval:        pubsub.DefaultDecayInterval,
+		DecayToZero:          pubsub.DefaultDecayToZero,
+		RetainScore:          time.Hour,
+	}
+	groupScoreThresholds = &pubsub.PeerScoreThresholds{
+		SkipAtomicValidation: true,
+		GossipThreshold:      -10,
+		PublishThreshold:     -50,
+		GraylistThreshold:    -80,
+	}
+	groupTopicScoreParams = &pubsub.TopicScoreParams{
+		SkipAtomicValidation:           true,
+		TopicWeight:                    1,
+		TimeInMeshQuantum:              time.Second,
+		InvalidMessageDeliveriesWeight: -10,
+		InvalidMessageDeliveriesDecay:  pubsub.ScoreParameterDecay(time.Hour),
+	}
 )
 
 type GPService struct {
 	ctx       context.Context
+	cancel    context.CancelFunc
 	h         host.Host
 	connector Connector
-	id        IdentityAPI
-	rch       chan string
+	mux       sync.Mutex
 	rooms     map[string]*ChatRoom
 	ps        *pubsub.PubSub
 	bus       Bus
 }
 
-func NewGPService(ctx context.Context, h host.Host, identity IdentityAPI, b Bus, ch chan string, c Connector) PubSubService {
+func NewGPService(ctx context.Context, h host.Host, b Bus, c Connector) (PubSubService, error) {
 	gpService := new(GPService)
-	gpService.ctx = ctx
-	gpService.rch = ch
+	gpService.ctx, gpService.cancel = context.WithCancel(ctx)
 	gpService.connector = c
 	gpService.h = h
 	gpService.bus = b
 
-	gpService.id = identity
-
-	ps, err := pubsub.NewGossipSub(ctx, h)
+	ps, err := pubsub.NewGossipSub(gpService.ctx, h, pubsub.WithPeerScore(groupScoreParams, groupScoreThresholds))
 	if err != nil {
-		panic(err)
+		gpService.cancel()
+		return nil, err
 	}
 	gpService.ps = ps
-
 	gpService.rooms = make(map[string]*ChatRoom)
-	go func() {
-		for chID := range gpService.rch {
-			gpService.JoinChatRoom(chID)
-		}
-	}()
+	return gpService, nil
+}
 
-	return gpService
+func (s *GPService) room(chatID string) (*ChatRoom, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	room, pres := s.rooms[chatID]
+	return room, pres
 }
 
 func (s *GPService) OnlinePeers(chatID string) []peer.ID {
-	return s.ps.ListPeers(topicName(s.rooms[chatID].roomName))
+	room, pres := s.room(chatID)
+	if !pres {
+		return nil
+	}
+	return room.topic.ListPeers()
 }
 
 func (s *GPService) Send(n PubSubEnvelop) {
-	room, pres := s.rooms[n.Topic]
+	room, pres := s.room(n.Topic)
 	switch n.Message.(type) {
 	case entity.Message:
 		msg := n.Message.(entity.Message)
@@ -74,6 +101,12 @@ func (s *GPService) Send(n PubSubEnvelop) {
 			log.Errorf("chatroom not present")
 			return
 		}
+		// gossipsub does not keep messages, nobody would get it
+		if len(room.topic.ListPeers()) == 0 {
+			log.Warnf("no member of %s online", n.Topic)
+			event.EmitMessageChange(s.bus, entity.Failed, string(msg.ID))
+			return
+		}
 
 		err := room.send(n.Message.Proto())
 		if err != nil {
@@ -89,56 +122,173 @@ func (s *GPService) Send(n PubSubEnvelop) {
 
 }
 
-func (s *GPService) Join(ChatID entity.ID, admins []entity.Contact) {
-	for _, v := range admins {
-		adder, _ := v.AdderInfo()
-		s.h.Peerstore().AddAddrs(adder.ID,
-			[]ma.Multiaddr{
-				ma.StringCast("/p2p/" + "12D3KooWBFpA7pCMBySBqtduBVkakVQ3bmmaeagB83WHoruBN9s9" + "/p2p-circuit/p2p/" + adder.ID.String()),
-				ma.StringCast("/p2p/" + "12D3KooWBFpA7pCMBySBqtduBVkakVQ3bmmaeagB83WHoruBN9s9" + "/p2p-circuit/p2p/" + adder.ID.String()),
-			},
-			time.Minute*5)
+// Join subscribes to the topic of a group chat and keeps connections to its members.
+func (s *GPService) Join(ChatID entity.ID, members []entity.Contact) {
+	for _, v := range members {
+		adder, err := v.AdderInfo()
+		if err != nil || adder.ID == s.h.ID() {
+			continue
+		}
 		s.connector.Need(ChatID.String(), *adder)
 	}
 
-	s.JoinChatRoom(ChatID.String())
+	err := s.JoinChatRoom(ChatID.String(), members)
+	if err != nil {
+		log.Errorf("can not join %s: %s", ChatID, err.Error())
+	}
 }
 
 // JoinChatRoom tries to subscribe to the PubSub topic for the room name, returning
 // a ChatRoom on success.
-func (s *GPService) JoinChatRoom(chatID string) error {
-	// join the pubsub topic
-	topic, err := s.ps.Join(topicName(chatID))
-	if err != nil {
-		return err
+func (s *GPService) JoinChatRoom(chatID string, members []entity.Contact) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if room, pres := s.rooms[chatID]; pres {
+		room.setMembers(members)
+		return nil
 	}
-
-	// and subscribe to it
-	sub, err := topic.Subscribe()
+	// join the pubsub topic and subscribe to it
+	topic, sub, err := s.joinTopic(topicName(chatID), s.validate, groupTopicScoreParams)
 	if err != nil {
 		return err
 	}
-
-	self, err := s.id.PeerID()
+	signals, signalSub, err := s.joinSignals(chatID)
 	if err != nil {
+		sub.Cancel()
+		topic.Close()
+		s.ps.UnregisterTopicValidator(topicName(chatID))
 		return err
 	}
+
 	cr := &ChatRoom{
-		ctx:   s.ctx,
-		topic: topic,
-		sub:   sub,
-		self:  self,
+		ctx:       s.ctx,
+		topic:     topic,
+		sub:       sub,
+		signals:   signals,
+		signalSub: signalSub,
+		roomName:  chatID,
+		self:      s.h.ID(),
 	}
+	cr.setMembers(members)
 
 	s.rooms[chatID] = cr
 	// start reading messages from the subscription in a loop
 	go cr.readLoop(s.bus)
-	cr.topic.Publish(cr.ctx, []byte(JOINED))
+	go cr.readSignals(s.bus)
 	return nil
 }
 
+// joinSignals subscribes to the topic of typing and presence of a group,
+// it is apart from the messages so that signals are never taken for one.
+func (s *GPService) joinSignals(chatID string) (*pubsub.Topic, *pubsub.Subscription, error) {
+	return s.joinTopic(signalTopicName(chatID), s.validateSignal, nil)
+}
+
+// joinTopic registers the validator of a topic, joins it and subscribes to
+// it. On error the validator is removed and the topic closed, so that the
+// topic can be joined again.
+func (s *GPService) joinTopic(name string, validator interface{}, params *pubsub.TopicScoreParams) (*pubsub.Topic, *pubsub.Subscription, error) {
+	err := s.ps.RegisterTopicValidator(name, validator)
+	if err != nil {
+		return nil, nil, err
+	}
+	topic, err := s.ps.Join(name)
+	if err != nil {
+		s.ps.UnregisterTopicValidator(name)
+		return nil, nil, err
+	}
+	if params != nil {
+		err = topic.SetScoreParams(params)
+	}
+	var sub *pubsub.Subscription
+	if err == nil {
+		sub, err = topic.Subscribe()
+	}
+	if err != nil {
+		topic.Close()
+		s.ps.UnregisterTopicValidator(name)
+		return nil, nil, err
+	}
+	return topic, sub, nil
+}
+
+// Signal publishes s to the members of a group that are online.
+func (s *GPService) Signal(chatID entity.ID, sig entity.Signal) error {
+	room, pres := s.room(chatID.String())
+	if !pres {
+		return ErrNotGroup
+	}
+	b, err := proto.Marshal(sig.Proto())
+	if err != nil {
+		return err
+	}
+	return room.signals.Publish(room.ctx, b)
+}
+
+// validate accepts only messages signed by a member of the group who also
+// published them.
+func (s *GPService) validate(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	chatID := (*msg.Topic)[len(topicName("")):]
+	room, pres := s.room(chatID)
+	if !pres {
+		return pubsub.ValidationIgnore
+	}
+	cm := new(pb.Message)
+	err := proto.Unmarshal(msg.Data, cm)
+	if err != nil {
+		log.Warnf("invalid group message from %s: %s", msg.GetFrom(), err.Error())
+		return pubsub.ValidationReject
+	}
+	m := entity.ToMessage(cm)
+	err = room.verify(msg.GetFrom(), m)
+	if err != nil {
+		log.Warnf("group message %s from %s rejected: %s", m.ID, msg.GetFrom(), err.Error())
+		event.EmitRejectedMessage(s.bus, m)
+		return pubsub.ValidationReject
+	}
+	return pubsub.ValidationAccept
+}
+
+// validateSignal accepts signals of the group published by its members,
+// pubsub signs every message with the key of the publisher.
+func (s *GPService) validateSignal(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+	chatID := (*msg.Topic)[len(signalTopicName("")):]
+	room, pres := s.room(chatID)
+	if !pres {
+		return pubsub.ValidationIgnore
+	}
+	sig := new(pb.Signal)
+	err := proto.Unmarshal(msg.Data, sig)
+	if err != nil || sig.GetChatId() != chatID {
+		return pubsub.ValidationReject
+	}
+	if !room.isMember(entity.ID(msg.GetFrom().String())) {
+		return pubsub.ValidationReject
+	}
+	return pubsub.ValidationAccept
+}
+
+// Leave unsubscribes from the topic of a group chat.
+func (s *GPService) Leave(chatID entity.ID) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	room, pres := s.rooms[chatID.String()]
+	if !pres {
+		return
+	}
+	delete(s.rooms, chatID.String())
+	room.close()
+	s.ps.UnregisterTopicValidator(topicName(chatID.String()))
+	s.ps.UnregisterTopicValidator(signalTopicName(chatID.String()))
+}
+
 func (s *GPService) Stop() {
-	s.ctx.Done()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, room := range s.rooms {
+		room.close()
+	}
+	s.cancel()
 }
 
 // ChatRoom represents a subscription to a single PubSub topic. Messages
@@ -149,9 +299,51 @@ type ChatRoom struct {
 	ctx   context.Context
 	topic *pubsub.Topic
 	sub   *pubsub.Subscription
+	// typing and presence of the members
+	signals   *pubsub.Topic
+	signalSub *pubsub.Subscription
 
 	roomName string
 	self     peer.ID
+	mux      sync.Mutex
+	members  map[entity.ID]bool
+}
+
+func (cr *ChatRoom) close() {
+	cr.sub.Cancel()
+	cr.topic.Close()
+	cr.signalSub.Cancel()
+	cr.signals.Close()
+}
+
+func (cr *ChatRoom) setMembers(members []entity.Contact) {
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+	cr.members = make(map[entity.ID]bool)
+	for _, m := range members {
+		cr.members[m.ID] = true
+	}
+}
+
+func (cr *ChatRoom) isMember(id entity.ID) bool {
+	cr.mux.Lock()
+	defer cr.mux.Unlock()
+	return cr.members[id]
+}
+
+// verify checks a group message is signed by a member who published it.
+func (cr *ChatRoom) verify(from peer.ID, m entity.Message) error {
+	if m.ChatID.String() != cr.roomName {
+		return ErrWrongChat
+	}
+	err := verifyMessage(from, m)
+	if err != nil {
+		return err
+	}
+	if !cr.isMember(m.Author.ID) {
+		return ErrNotMember
+	}
+	return nil
 }
 
 // Publish sends a message to the pubsub topic.
@@ -170,15 +362,11 @@ func (cr *ChatRoom) readLoop(bus Bus) {
 		if err != nil {
 			return
 		}
-		// only forward messages delivered by others
-		if msg.ReceivedFrom == cr.self {
+		// only forward messages published by others
+		if msg.GetFrom() == cr.self {
 			continue
 		}
 
-		if bytes.Equal(msg.Data, []byte(JOINED)) {
-			log.Debugf("new peer joined: %v", msg.ID)
-			continue
-		}
 		log.Debugf("new group message arrived: %v", msg.ID)
 		cm := new(pb.Message)
 		err = proto.Unmarshal(msg.Data, cm)
@@ -187,13 +375,36 @@ func (cr *ChatRoom) readLoop(bus Bus) {
 		}
 		// send valid messages onto the Messages channel
 
-		event.EmitNewMessage(bus, entity.ToMessage(cm))
+		event.EmitGroupMessage(bus, entity.ToMessage(cm))
+	}
+}
+
+// readSignals pulls the signals of others from the signal topic.
+func (cr *ChatRoom) readSignals(bus Bus) {
+	for {
+		msg, err := cr.signalSub.Next(cr.ctx)
+		if err != nil {
+			return
+		}
+		if msg.GetFrom() == cr.self {
+			continue
+		}
+		sig := new(pb.Signal)
+		err = proto.Unmarshal(msg.Data, sig)
 		if err != nil {
-			panic("bus not working")
+			continue
 		}
+		s := entity.ToSignal(sig)
+		s.From = entity.ID(msg.GetFrom().String())
+		s.At = time.Now().Unix()
+		event.EmitSignalReceived(bus, s)
 	}
 }
 
 func topicName(roomName string) string {
 	return "chat-room:" + roomName
 }
+
+func signalTopicName(roomName string) string {
+	return "chat-signal:" + roomName
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	lpevent "github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// waitMesh waits until every service sees n other peers on the topic.
+func waitMesh(t *testing.T, services []*GPService, chatID entity.ID, n int) {
+	for _, s := range services {
+		require.Eventually(t, func() bool {
+			return len(s.OnlinePeers(chatID.String())) == n
+		}, 10*time.Second, 50*time.Millisecond)
+	}
+	// let a heartbeat pass, messages published right after the subscription
+	// arrives may not reach the new peer
+	time.Sleep(2 * time.Second)
+}
+
+func TestGroupMessaging(t *testing.T) {
+	peers := mockPeers(t, 3)
+	members := make([]entity.Contact, 0)
+	for _, p := range peers {
+		members = append(members, p.me)
+	}
+	chat := entity.NewGroupChat("group", members, members[:1])
+
+	services := make([]*GPService, 0)
+	subs := make([]lpevent.Subscription, 0)
+	for _, p := range peers {
+		sub, err := p.bus.Subscribe(new(event.MessageEventObj))
+		require.NoError(t, err)
+		defer sub.Close()
+		subs = append(subs, sub)
+		gps, err := NewGPService(context.Background(), p.host, p.bus, NewConnector(p.host))
+		require.NoError(t, err)
+		defer gps.Stop()
+		gps.Join(chat.ID, chat.Members)
+		services = append(services, gps.(*GPService))
+	}
+	waitMesh(t, services, chat.ID, len(peers)-1)
+
+	for i, p := range peers {
+		msg := p.message(t, chat.ID, fmt.Sprint("hello from ", i))
+		services[i].Send(PubSubEnvelop{Topic: chat.ID.String(), Message: msg, CreatedAt: msg.CreatedAt})
+		evt := nextMessageEvent(t, subs[i], event.ChangeStatus)
+		require.Equal(t, entity.Sent, evt.GetAction())
+		for j := range peers {
+			if j == i {
+				continue
+			}
+			evt := nextMessageEvent(t, subs[j], event.NewGroupMessage)
+			got := evt.GetPayload().(entity.Message)
+			require.Equal(t, msg.Text, got.Text)
+			require.Equal(t, p.me.ID, got.Author.ID)
+		}
+	}
+
+	// not joined
+	msg := peers[0].message(t, "other", "hello")
+	services[0].Send(PubSubEnvelop{Topic: "other", Message: msg, CreatedAt: msg.CreatedAt})
+	evt := nextMessageEvent(t, subs[0], event.ChangeStatus)
+	require.Equal(t, entity.Failed, evt.GetAction())
+}
+
+func TestGroupValidator(t *testing.T) {
+	peers := mockPeers(t, 3)
+	members := []entity.Contact{peers[0].me, peers[1].me}
+	chat := entity.NewGroupChat("group", members, members[:1])
+
+	services := make([]*GPService, 0)
+	subs := make([]lpevent.Subscription, 0)
+	for _, p := range peers[:2] {
+		sub, err := p.bus.Subscribe(new(event.MessageEventObj))
+		require.NoError(t, err)
+		defer sub.Close()
+		subs = append(subs, sub)
+		gps, err := NewGPService(context.Background(), p.host, p.bus, NewConnector(p.host))
+		require.NoError(t, err)
+		defer gps.Stop()
+		gps.Join(chat.ID, chat.Members)
+		services = append(services, gps.(*GPService))
+	}
+
+	// outsider knows the group id and joins the topic without validation
+	ps, err := pubsub.NewGossipSub(context.Background(), peers[2].host)
+	require.NoError(t, err)
+	topic, err := ps.Join(topicName(chat.ID.String()))
+	require.NoError(t, err)
+	_, err = topic.Subscribe()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(topic.ListPeers()) == 2
+	}, 10*time.Second, 50*time.Millisecond)
+	waitMesh(t, services, chat.ID, 2)
+
+	publish := func(m entity.Message) {
+		b, err := proto.Marshal(m.Proto())
+		require.NoError(t, err)
+		require.NoError(t, topic.Publish(context.Background(), b))
+	}
+	// signed by a non member
+	publish(peers[2].message(t, chat.ID, "outsider"))
+	// a member's message published by someone else
+	publish(peers[1].message(t, chat.ID, "replayed"))
+	require.NoError(t, topic.Publish(context.Background(), []byte("garbage")))
+	nextMessageEvent(t, subs[0], event.RejectedMessage)
+	nextMessageEvent(t, subs[0], event.RejectedMessage)
+
+	msg := peers[1].message(t, chat.ID, "member")
+	services[1].Send(PubSubEnvelop{Topic: chat.ID.String(), Message: msg, CreatedAt: msg.CreatedAt})
+	evt := nextMessageEvent(t, subs[0], event.NewGroupMessage)
+	require.Equal(t, msg.Text, evt.GetPayload().(entity.Message).Text)
+
+	room, _ := services[0].room(chat.ID.String())
+	tampered := peers[1].message(t, chat.ID, "hello")
+	tampered.Text = "bye"
+	require.ErrorIs(t, room.verify(peers[1].host.ID(), tampered), entity.ErrInvalidSignature)
+	other := peers[1].message(t, "other", "hello")
+	require.ErrorIs(t, room.verify(peers[1].host.ID(), other), ErrWrongChat)
+	outsider := peers[2].message(t, chat.ID, "hello")
+	require.ErrorIs(t, room.verify(peers[2].host.ID(), outsider), ErrNotMember)
+}
+
+func TestGroupSignals(t *testing.T) {
+	peers := mockPeers(t, 3)
+	members := []entity.Contact{peers[0].me, peers[1].me}
+	chat := entity.NewGroupChat("group", members, members[:1])
+
+	services := make([]*GPService, 0)
+	for _, p := range peers[:2] {
+		gps, err := NewGPService(context.Background(), p.host, p.bus, NewConnector(p.host))
+		require.NoError(t, err)
+		defer gps.Stop()
+		gps.Join(chat.ID, chat.Members)
+		services = append(services, gps.(*GPService))
+	}
+	sub, err := peers[0].bus.Subscribe(new(event.SignalEventObj))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// outsider knows the group id and publishes without validation
+	ps, err := pubsub.NewGossipSub(context.Background(), peers[2].host)
+	require.NoError(t, err)
+	topic, err := ps.Join(signalTopicName(chat.ID.String()))
+	require.NoError(t, err)
+	_, err = topic.Subscribe()
+	require.NoError(t, err)
+	room, _ := services[0].room(chat.ID.String())
+	require.Eventually(t, func() bool {
+		return len(room.signals.ListPeers()) == 2
+	}, 10*time.Second, 50*time.Millisecond)
+	waitMesh(t, services, chat.ID, 1)
+
+	b, err := proto.Marshal(entity.Signal{ChatID: chat.ID, Kind: entity.TypingStarted}.Proto())
+	require.NoError(t, err)
+	require.NoError(t, topic.Publish(context.Background(), b))
+	require.NoError(t, services[1].Signal(chat.ID, entity.Signal{ChatID: chat.ID, Kind: entity.Online, TTL: 60}))
+
+	select {
+	case e := <-sub.Out():
+		evt := e.(event.SignalEventObj)
+		require.Equal(t, event.SignalReceived, evt.GetName())
+		require.Equal(t, event.Online, evt.GetAction())
+		require.Equal(t, peers[1].me.ID, evt.GetPayload().From)
+		require.Equal(t, int64(60), evt.GetPayload().TTL)
+	case <-time.After(10 * time.Second):
+		t.Fatal("signal not received")
+	}
+	require.ErrorIs(t, services[0].Signal("other", entity.Signal{ChatID: "other"}), ErrNotGroup)
+}
+
+func TestGroupJoinFailure(t *testing.T) {
+	peers := mockPeers(t, 1)
+	chat := entity.NewGroupChat("group", []entity.Contact{peers[0].me}, []entity.Contact{peers[0].me})
+	svc, err := NewGPService(context.Background(), peers[0].host, peers[0].bus, NewConnector(peers[0].host))
+	require.NoError(t, err)
+	defer svc.Stop()
+	gps := svc.(*GPService)
+
+	// the signal topic can not be joined, nothing of the join is left
+	blocker := func(context.Context, peer.ID, *pubsub.Message) bool { return true }
+	require.NoError(t, gps.ps.RegisterTopicValidator(signalTopicName(chat.ID.String()), blocker))
+	require.Error(t, gps.JoinChatRoom(chat.ID.String(), chat.Members))
+	_, pres := gps.room(chat.ID.String())
+	require.False(t, pres)
+
+	require.NoError(t, gps.ps.UnregisterTopicValidator(signalTopicName(chat.ID.String())))
+	require.NoError(t, gps.JoinChatRoom(chat.ID.String(), chat.Members))
+	_, pres = gps.room(chat.ID.String())
+	require.True(t, pres)
+}
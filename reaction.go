@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+)
+
+var ErrInvalidEmoji = errors.New("reaction must be a short emoji")
+
+// longest reaction, in runes, enough for emoji sequences
+const maxEmojiLength = 8
+
+// React adds emoji to a message for every member of the chat, reacting
+// twice with the same emoji counts once.
+func (c *Chat) React(msgID entity.ID, emoji string) (entity.Message, error) {
+	return c.react(msgID, entity.React{Target: msgID, Emoji: emoji})
+}
+
+// Unreact takes back my emoji on a message.
+func (c *Chat) Unreact(msgID entity.ID, emoji string) (entity.Message, error) {
+	return c.react(msgID, entity.React{Target: msgID, Emoji: emoji, Remove: true})
+}
+
+// react signs a reaction, applies it and sends it to the members.
+func (c *Chat) react(msgID entity.ID, r entity.React) (entity.Message, error) {
+	if r.Emoji == "" || !utf8.ValidString(r.Emoji) || utf8.RuneCountInString(r.Emoji) > maxEmojiLength {
+		return entity.Message{}, ErrInvalidEmoji
+	}
+	target, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return target, err
+	}
+	me, err := c.Identity.Get()
+	if err != nil {
+		return target, err
+	}
+	sk, err := c.Identity.PrivKey()
+	if err != nil {
+		return target, err
+	}
+	chat, err := c.chRepo.GetByID(target.ChatID)
+	if err != nil {
+		return target, err
+	}
+	msg := entity.Message{
+		ID:        entity.ID(uuid.New().String()),
+		ChatID:    target.ChatID,
+		CreatedAt: time.Now().UTC().Unix(),
+		Author:    *me.ToContact(),
+		ChatType:  chat.Type,
+		React:     &r,
+	}
+	err = msg.Sign(sk)
+	if err != nil {
+		return target, err
+	}
+	target, err = c.applyReaction(chat, msg)
+	if err != nil {
+		return target, err
+	}
+	c.broadcast(chat, msg)
+	return target, nil
+}
+
+// reacted applies a reaction received from a member.
+func (c *Chat) reacted(msg entity.Message, viaTopic bool) error {
+	chat, err := c.chRepo.GetByID(msg.ChatID)
+	if err != nil {
+		return err
+	}
+	err = checkOrigin(chat, msg, viaTopic)
+	if err != nil {
+		return err
+	}
+	_, err = c.applyReaction(chat, msg)
+	return err
+}
+
+// applyReaction records the reaction of msg on its target. The latest
+// reaction of an author with an emoji wins.
+func (c *Chat) applyReaction(chat entity.ChatInfo, msg entity.Message) (entity.Message, error) {
+	r := msg.React
+	target, err := c.mRepo.GetByID(r.Target)
+	if err != nil {
+		return target, err
+	}
+	if target.ChatID != msg.ChatID {
+		return target, ErrWrongChat
+	}
+	if !chat.IsMember(msg.Author.ID) {
+		return target, ErrNotMember
+	}
+	if target.Deleted {
+		return target, ErrDeleted
+	}
+	changed, err := c.mRepo.React(target.ID, msg.Author.ID, *r, msg.CreatedAt)
+	if err != nil {
+		return target, err
+	}
+	target, err = c.mRepo.GetByID(target.ID)
+	if err != nil {
+		return target, err
+	}
+	if changed {
+		event.EmitMessageUpdate(c.bus, event.MessageReacted, target)
+	}
+	return target, nil
+}
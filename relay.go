@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/discovery"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/client"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var ErrNoDiscovery = errors.New("relay discovery needs the DHT")
+var ErrNoRelay = errors.New("peer does not advertise a relay")
+
+// namespace a peer advertises its relay addresses under
+const relayNamespace = "/hood-chat/relay/"
+
+// retry interval of a failed reservation
+var reservationRetry = 30 * time.Second
+
+// RelayHost is a host that keeps circuit relay v2 reservations, Builders
+// return it so contacts behind NAT can be found through their relays.
+type RelayHost interface {
+	DiscoveryHost
+	Relays() *Relays
+}
+
+type relayHost struct {
+	host.Host
+	relays *Relays
+}
+
+func (h *relayHost) Relays() *Relays {
+	return h.relays
+}
+
+func (h *relayHost) Discovery() discovery.Discovery {
+	return h.relays.disc
+}
+
+func (h *relayHost) Close() error {
+	h.relays.Close()
+	return h.Host.Close()
+}
+
+// Relays reserves slots on the configured relays and advertises the
+// resulting circuit addresses in the DHT.
+type Relays struct {
+	h      host.Host
+	disc   discovery.Discovery
+	relays []peer.AddrInfo
+	ctx    context.Context
+	cancel context.CancelFunc
+	mux    sync.Mutex
+	// circuit addresses by relay, only for relays with a reservation
+	circuits map[peer.ID][]ma.Multiaddr
+}
+
+func NewRelays(relays []peer.AddrInfo) *Relays {
+	r := &Relays{relays: relays, circuits: make(map[peer.ID][]ma.Multiaddr)}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+// AddrsFactory adds the circuit addresses of the reservations to the host
+// addresses, pass it to libp2p.AddrsFactory.
+func (r *Relays) AddrsFactory(addrs []ma.Multiaddr) []ma.Multiaddr {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	res := append([]ma.Multiaddr{}, addrs...)
+	for _, c := range r.circuits {
+		res = append(res, c...)
+	}
+	return res
+}
+
+// Start makes the reservations on behalf of h, disc is nil when relay
+// addresses are not advertised.
+func (r *Relays) Start(h host.Host, disc discovery.Discovery) {
+	r.h = h
+	r.disc = disc
+	for _, relay := range r.relays {
+		go r.keep(relay)
+	}
+}
+
+func (r *Relays) Close() {
+	r.cancel()
+}
+
+// keep reserves a slot on relay and refreshes it before it expires.
+func (r *Relays) keep(relay peer.AddrInfo) {
+	for {
+		wait := reservationRetry
+		rsvp, err := client.Reserve(r.ctx, r.h, relay)
+		if err != nil {
+			log.Debugf("reservation on %s failed: %s", relay.ID, err.Error())
+			r.setCircuits(relay.ID, nil)
+		} else {
+			r.h.ConnManager().Protect(relay.ID, "relay")
+			r.setCircuits(relay.ID, r.circuitAddrs(relay, rsvp))
+			r.advertise()
+			wait = time.Until(rsvp.Expiration) / 2
+		}
+		select {
+		case <-time.After(wait):
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Relays) setCircuits(relay peer.ID, addrs []ma.Multiaddr) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if len(addrs) == 0 {
+		delete(r.circuits, relay)
+		return
+	}
+	r.circuits[relay] = addrs
+}
+
+// circuitAddrs are our addresses through relay. Relays only vouch for
+// public addresses, otherwise the known addresses of the relay are used.
+func (r *Relays) circuitAddrs(relay peer.AddrInfo, rsvp *client.Reservation) []ma.Multiaddr {
+	circuit := ma.StringCast("/p2p-circuit")
+	if len(rsvp.Addrs) > 0 {
+		res := make([]ma.Multiaddr, 0, len(rsvp.Addrs))
+		for _, a := range rsvp.Addrs {
+			res = append(res, a.Encapsulate(circuit))
+		}
+		return res
+	}
+	addrs := relay.Addrs
+	if len(addrs) == 0 {
+		addrs = r.h.Peerstore().Addrs(relay.ID)
+	}
+	self, err := ma.NewComponent("p2p", relay.ID.String())
+	if err != nil {
+		return nil
+	}
+	res := make([]ma.Multiaddr, 0, len(addrs))
+	for _, a := range addrs {
+		res = append(res, a.Encapsulate(self).Encapsulate(circuit))
+	}
+	return res
+}
+
+func (r *Relays) advertise() {
+	if r.disc == nil {
+		return
+	}
+	go func() {
+		_, err := r.disc.Advertise(r.ctx, relayNamespace+r.h.ID().String())
+		if err != nil {
+			log.Debugf("can not advertise relay addresses: %s", err.Error())
+		}
+	}()
+}
+
+// Find returns the relay addresses advertised by p and adds them to the
+// peerstore.
+func (r *Relays) Find(ctx context.Context, p peer.ID) (peer.AddrInfo, error) {
+	if r.disc == nil {
+		return peer.AddrInfo{}, ErrNoDiscovery
+	}
+	peers, err := r.disc.FindPeers(ctx, relayNamespace+p.String())
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	for pi := range peers {
+		// only p can put its own provider record
+		if pi.ID != p || len(pi.Addrs) == 0 {
+			continue
+		}
+		r.h.Peerstore().AddAddrs(p, pi.Addrs, peerstore.TempAddrTTL)
+		return pi, nil
+	}
+	return peer.AddrInfo{}, ErrNoRelay
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+// relayBuilder creates a public circuit v2 relay that is also a DHT server.
+type relayBuilder struct{}
+
+func (relayBuilder) Create(opt Option) (host.Host, error) {
+	lpOpt := append(opt.LpOpt,
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		libp2p.EnableRelayService(),
+		libp2p.ForceReachabilityPublic(),
+	)
+	h, err := libp2p.New(lpOpt...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = dht.New(context.Background(), h, dht.Mode(dht.ModeServer))
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func isCircuit(a ma.Multiaddr) bool {
+	_, err := a.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+func TestRelayReservation(t *testing.T) {
+	relay, err := relayBuilder{}.Create(Option{})
+	require.NoError(t, err)
+	defer relay.Close()
+	relayAddr := relay.Addrs()[0].String() + "/p2p/" + relay.ID().String()
+
+	profile := LocalNetwork()
+	profile.Bootstrap = []string{relayAddr}
+	profile.Relays = []string{relayAddr}
+	profile.DHT = true
+	profile.AutoRelay = true
+
+	// a peer behind NAT, it can only be reached through the relay
+	natted, err := DefaultRoutedHost{}.Create(Option{Network: profile, LpOpt: []libp2p.Option{libp2p.NoListenAddrs, libp2p.EnableRelay()}})
+	require.NoError(t, err)
+	defer natted.Close()
+	require.Implements(t, (*RelayHost)(nil), natted)
+	require.Eventually(t, func() bool {
+		addrs := natted.Addrs()
+		return len(addrs) > 0 && isCircuit(addrs[0])
+	}, 10*time.Second, 50*time.Millisecond)
+
+	other, err := DefaultRoutedHost{}.Create(Option{Network: profile})
+	require.NoError(t, err)
+	defer other.Close()
+
+	var found peer.AddrInfo
+	require.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		found, err = other.(RelayHost).Relays().Find(ctx, natted.ID())
+		return err == nil
+	}, 20*time.Second, 200*time.Millisecond)
+	for _, a := range found.Addrs {
+		require.True(t, isCircuit(a), a.String())
+	}
+
+	// the connector dials through the advertised relay
+	c := newConnector(other)
+	c.Need("test", peer.AddrInfo{ID: natted.ID()})
+	require.Eventually(t, func() bool {
+		for _, conn := range other.Network().ConnsToPeer(natted.ID()) {
+			if isCircuit(conn.RemoteMultiaddr()) {
+				return true
+			}
+		}
+		return false
+	}, 20*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"github.com/hood-chat/core/entity"
+)
+
+// longest quoted text, in runes
+const snippetLength = 100
+
+// Reply answers msgID with content, the answered message is quoted. In a
+// thread, or when inThread is set, the reply joins the thread of msgID.
+func (c *Chat) Reply(msgID entity.ID, content string, inThread bool) (*entity.Message, error) {
+	target, err := c.mRepo.GetByID(msgID)
+	if err != nil {
+		return nil, err
+	}
+	draft := entity.Message{
+		Text: content,
+		ReplyTo: &entity.Reply{
+			ID:      target.ID,
+			Author:  target.Author,
+			Snippet: snippet(target.Text),
+		},
+		ThreadID: target.ThreadID,
+	}
+	if inThread && draft.ThreadID == "" {
+		draft.ThreadID = target.ID
+	}
+	return c.send(target.ChatID, draft)
+}
+
+// Thread returns the first message of a thread followed by its replies,
+// the oldest first. The page counts and points at the replies only, the
+// first message heads the page that starts the thread and is missing if we
+// never got it.
+func (c *Chat) Thread(threadID entity.ID, page entity.Page) (entity.MessagePage, error) {
+	replies, info, err := c.mRepo.Thread(threadID, page)
+	if err != nil {
+		return entity.MessagePage{}, err
+	}
+	res := make(entity.MessageSlice, 0, len(replies)+1)
+	if page.After == "" && (page.Before == "" || !info.More) {
+		root, err := c.mRepo.GetByID(threadID)
+		if err == nil {
+			res = append(res, root)
+		}
+	}
+	return entity.MessagePage{Items: append(res, replies...), PageInfo: info}, nil
+}
+
+func snippet(text string) string {
+	r := []rune(text)
+	if len(r) <= snippetLength {
+		return text
+	}
+	return string(r[:snippetLength]) + "…"
+}
@@ -2,6 +2,9 @@ package repo
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/hood-chat/core/entity"
 	"github.com/hood-chat/core/store"
@@ -10,102 +13,96 @@ import (
 var ErrNotImplemented = errors.New("not implemented")
 var ErrNotSupported = errors.New("not supported")
 
-
-//TODO: after adding IOption look like we can remove GetAll and GetByID
 type IRepo[C any] interface {
 	Get() (C, error)
 	GetByID(id entity.ID) (C, error)
-	GetAll(opt IOption) ([]C, error)
+	GetAll(page entity.Page, filter Filter) ([]C, entity.PageInfo, error)
 	Put(C) error
 	Add(C) error
 }
 
-type IOption interface {
-	Skip() int
-	Limit() int
-	AddFilter(field string, value interface{})
-	Filters() Filter
-}
-
+// Filter narrows GetAll by field, each repo tells the fields it knows.
 type Filter map[string]interface{}
 
-type Option struct {
-	skip    int
-	limit   int
-	filters Filter
+func NewChatRepo(store *store.Store) IRepo[entity.ChatInfo] {
+	return ChatRepo{
+		store: store,
+	}
 }
 
-func NewOption(skip int, limit int) IOption {
-	return Option{skip, limit, make(Filter)}
+type ChatRepo struct {
+	store *store.Store
+}
+
+// seek returns where the store reads page p from.
+func seek(p entity.Page) (store.Seek, error) {
+	s := store.Seek{Limit: p.Limit}
+	c := p.After
+	if p.Before != "" {
+		if p.After != "" {
+			return s, entity.ErrInvalidCursor
+		}
+		c = p.Before
+		s.Before = true
+	}
+	if c == "" {
+		return s, nil
+	}
+	key, id, err := c.Decode()
+	if err != nil {
+		return s, err
+	}
+	s.Key, s.ID, s.From = key, id.String(), true
+	return s, nil
 }
 
-func (o Option) Skip() int {
-	return o.skip
+// pageInfo returns the cursors of the first and last of items.
+func pageInfo[T any](items []T, more bool, cursor func(T) entity.Cursor) entity.PageInfo {
+	info := entity.PageInfo{More: more}
+	if len(items) > 0 {
+		info.Start = cursor(items[0])
+		info.End = cursor(items[len(items)-1])
+	}
+	return info
 }
 
-func (o Option) Limit() int {
-	return o.limit
+func chatCursor(c entity.ChatInfo) entity.Cursor {
+	return entity.NewCursor(c.LatestAt, c.ID)
 }
 
-func (o Option) AddFilter(n string, s interface{}) {
-	o.filters[n] = s
+func messageCursor(m entity.Message) entity.Cursor {
+	return entity.NewCursor(m.CreatedAt, m.ID)
 }
 
-func (o Option) Filters() Filter {
-	return o.filters
+func contactCursor(c entity.Contact) entity.Cursor {
+	return entity.NewCursor(0, c.ID)
 }
 
-func NewChatRepo(store *store.Store) IRepo[entity.ChatInfo] {
-	return ChatRepo{
-		store: store,
-	}
+// searchCursor keys a result by its score, scores are not negative so their
+// bits sort as they do.
+func searchCursor(r entity.SearchResult) entity.Cursor {
+	return entity.NewCursor(int64(math.Float64bits(r.Score)), r.Message.ID)
 }
 
-type ChatRepo struct {
-	store *store.Store
+func requestCursor(r entity.ChatRequest) entity.Cursor {
+	return entity.NewCursor(r.CreatedAt, r.Chat.ID)
 }
 
-func (c ChatRepo) GetAll(opt IOption) ([]entity.ChatInfo, error) {
-	chl, err := c.store.ChatList(opt.Skip(), opt.Limit())
+// GetAll returns a page of the chats, the latest active first.
+func (c ChatRepo) GetAll(page entity.Page, filter Filter) ([]entity.ChatInfo, entity.PageInfo, error) {
+	p, err := seek(page)
 	if err != nil {
-		return nil, err
+		return nil, entity.PageInfo{}, err
+	}
+	chl, more, err := c.store.ChatList(p)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
 	}
-	ci := make([]entity.ChatInfo, 0)
+	ci := make([]entity.ChatInfo, 0, len(chl))
 	for _, val := range chl {
-		unread, err := c.store.ChatUnreadCount(val.ID);if err != nil {unread = 0}
-
-		latestText := ""
-		latestMsg, err := c.store.ChatMessages(val.ID,0,1);
-		if err == nil && len(latestMsg) > 0 {
-			latestText = latestMsg[0].Text
-		}
-
-		members := make([]entity.Contact, 0)
-		for _, me := range val.Members {
-			members = append(members, entity.Contact{
-				ID:   entity.ID(me.ID),
-				Name: me.Name,
-			})
-		}
-
-		admins := make([]entity.Contact, 0)
-		for _, me := range val.Admins {
-			admins = append(admins, entity.Contact{
-				ID:   entity.ID(me.ID),
-				Name: me.Name,
-			})
-		}
-		ci = append(ci, entity.ChatInfo{
-			ID:      entity.ID(val.ID),
-			Name:    val.Name,
-			Members: members,
-			Type: val.Type,
-			Unread: unread,
-			LatestText: latestText,
-			Admins: admins,
-		})
+		ci = append(ci, fromBHChat(val))
 	}
-	return ci, nil
+	return ci, pageInfo(ci, more, chatCursor), nil
 }
 
 func (c ChatRepo) GetByID(id entity.ID) (entity.ChatInfo, error) {
@@ -113,41 +110,10 @@ func (c ChatRepo) GetByID(id entity.ID) (entity.ChatInfo, error) {
 	if err != nil {
 		return entity.ChatInfo{}, err
 	}
-	unread, err := c.store.ChatUnreadCount(string(id));if err != nil {unread = 0}
-
-	latestText := ""
-	latestMsg, err := c.store.ChatMessages(id.String(),0,1);
-	if err == nil && len(latestMsg) > 0 {
-		latestText = latestMsg[0].Text
-	}
-
-	members := make([]entity.Contact, 0)
-	for _, me := range ct.Members {
-		members = append(members, entity.Contact{
-			ID:   entity.ID(me.ID),
-			Name: me.Name,
-		})
-	}
-
-	admins := make([]entity.Contact, 0)
-	for _, me := range ct.Admins {
-		admins = append(admins, entity.Contact{
-			ID:   entity.ID(me.ID),
-			Name: me.Name,
-		})
-	}
-	return entity.ChatInfo{
-		ID:      entity.ID(ct.ID),
-		Name:    ct.Name,
-		Members: members,
-		Type:    ct.Type,
-		Unread: unread,
-		LatestText: latestText,
-		Admins: admins,
-	}, nil
+	return fromBHChat(ct), nil
 }
 
-func (c ChatRepo) Add(chat entity.ChatInfo) error {
+func toBHChat(chat entity.ChatInfo) store.BHChat {
 	m := []store.BHContact{}
 	for _, val := range chat.Members {
 		m = append(m, store.BHContact{ID: val.ID.String(),Name: val.Name})
@@ -156,22 +122,27 @@ func (c ChatRepo) Add(chat entity.ChatInfo) error {
 	for _, val := range chat.Admins {
 		a = append(a, store.BHContact{ID: val.ID.String(),Name: val.Name})
 	}
-	ci := store.BHChat{
+	return store.BHChat{
 		ID:      string(chat.ID),
 		Name:    chat.Name,
 		Members: m,
 		Type:    chat.Type,
 		Admins:  a,
+		Expiry:  chat.Expiry,
 	}
-	err := c.store.InsertChat(ci)
+}
+
+func (c ChatRepo) Add(chat entity.ChatInfo) error {
+	err := c.store.InsertChat(toBHChat(chat))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Put updates name, members and admins of a stored chat.
 func (c ChatRepo) Put(chat entity.ChatInfo) error {
-	return ErrNotImplemented
+	return c.store.UpdateChat(toBHChat(chat))
 }
 
 func (c ChatRepo) Get() (entity.ChatInfo, error) {
@@ -182,28 +153,144 @@ type MessageRepo struct {
 	store store.Store
 }
 
-func NewMessageRepo(store *store.Store) IRepo[entity.Message] {
+func NewMessageRepo(store *store.Store) MessageRepo {
 	return MessageRepo{
 		store: *store,
 	}
 }
 
 func (m MessageRepo) Add(msg entity.Message) error {
-	tmsg := store.BHTextMessage{
-		ID:        string(msg.ID),
-		ChatID:    string(msg.ChatID),
-		CreatedAt: msg.CreatedAt,
-		Text:      msg.Text,
-		Status:    entity.Status(msg.Status),
-		Author:    store.BHContact{Name: msg.Author.Name, ID: string(msg.Author.ID)},
-	}
-	err := m.store.InsertTextMessage(tmsg)
+	err := m.store.InsertTextMessage(toBHMessage(msg))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 func (m MessageRepo) Put(msg entity.Message) error {
+	return m.store.UpdateMessage(toBHMessage(msg))
+}
+func (m MessageRepo) GetByID(id entity.ID) (entity.Message, error) {
+	bhmsg, err := m.store.MsgByID(id.String())
+	if err != nil {
+		return entity.Message{}, err
+	}
+	return fromBHMessage(bhmsg), nil
+}
+// GetAll returns a page of the messages of the chat set by the ChatID
+// filter, the newest first. The Status filter keeps the messages with one
+// of the statuses.
+func (m MessageRepo) GetAll(page entity.Page, filter Filter) ([]entity.Message, entity.PageInfo, error) {
+	messages := make([]entity.Message, 0)
+	chID, pres := filter["ChatID"].(string)
+	if !pres {
+		return nil, entity.PageInfo{}, ErrNotSupported
+	}
+	p, err := seek(page)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	status, _ := filter["Status"].([]entity.Status)
+	bhm, more, err := m.store.ChatMessages(string(chID), p, status...)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	for _, m := range bhm {
+		messages = append(messages, fromBHMessage(m))
+	}
+	return messages, pageInfo(messages, more, messageCursor), nil
+}
+
+// Thread returns a page of the replies in a thread, the oldest first.
+func (m MessageRepo) Thread(threadID entity.ID, page entity.Page) ([]entity.Message, entity.PageInfo, error) {
+	p, err := seek(page)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	bhm, more, err := m.store.ThreadMessages(threadID.String(), p)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	messages := make([]entity.Message, 0, len(bhm))
+	for _, m := range bhm {
+		messages = append(messages, fromBHMessage(m))
+	}
+	return messages, pageInfo(messages, more, messageCursor), nil
+}
+
+// React records a reaction to the message, it returns whether the
+// reactions changed.
+func (m MessageRepo) React(msgID entity.ID, author entity.ID, r entity.React, at int64) (bool, error) {
+	return m.store.ReactToMessage(msgID.String(), store.BHReaction{Emoji: r.Emoji, Author: author.String(), At: at, Removed: r.Remove})
+}
+
+// Search returns a page of the messages with all terms, the best matches
+// first. The filters are ChatID, Authors, Since and Until.
+func (m MessageRepo) Search(terms []string, page entity.Page, filter Filter) ([]entity.SearchResult, entity.PageInfo, error) {
+	p, err := seek(page)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	q := store.SearchQuery{Terms: terms}
+	if chatID, ok := filter["ChatID"].(entity.ID); ok {
+		q.ChatID = chatID.String()
+	}
+	if authors, ok := filter["Authors"].([]entity.ID); ok {
+		for _, a := range authors {
+			q.Authors = append(q.Authors, a.String())
+		}
+	}
+	q.Since, _ = filter["Since"].(int64)
+	q.Until, _ = filter["Until"].(int64)
+	hits, more, err := m.store.SearchMessages(q, p)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	res := make([]entity.SearchResult, 0, len(hits))
+	for _, h := range hits {
+		res = append(res, entity.SearchResult{Message: fromBHMessage(h.Msg), Score: h.Score})
+	}
+	return res, pageInfo(res, more, searchCursor), nil
+}
+
+// Sweep deletes the expired messages every interval until stop is called,
+// expired gets the deleted messages.
+func (m MessageRepo) Sweep(interval time.Duration, expired func(entity.MessageSlice)) (stop func()) {
+	return m.store.StartSweeper(interval, func(msgs []store.BHTextMessage) {
+		res := make(entity.MessageSlice, 0, len(msgs))
+		for _, msg := range msgs {
+			res = append(res, fromBHMessage(msg))
+		}
+		expired(res)
+	})
+}
+
+// countReactions groups the reactions by emoji, in the order they were
+// first used.
+func countReactions(rs []store.BHReaction) []entity.Reaction {
+	var res []entity.Reaction
+	index := make(map[string]int)
+	for _, r := range rs {
+		if r.Removed {
+			continue
+		}
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(res)
+			index[r.Emoji] = i
+			res = append(res, entity.Reaction{Emoji: r.Emoji})
+		}
+		res[i].Count++
+		res[i].Authors = append(res[i].Authors, entity.ID(r.Author))
+	}
+	return res
+}
+
+// Delete removes the message locally.
+func (m MessageRepo) Delete(id entity.ID) error {
+	return m.store.DeleteMessage(id.String())
+}
+
+func toBHMessage(msg entity.Message) store.BHTextMessage {
 	tmsg := store.BHTextMessage{
 		ID:        string(msg.ID),
 		ChatID:    string(msg.ChatID),
@@ -211,52 +298,46 @@ func (m MessageRepo) Put(msg entity.Message) error {
 		Text:      msg.Text,
 		Status:    entity.Status(msg.Status),
 		Author:    store.BHContact{Name: msg.Author.Name, ID: string(msg.Author.ID)},
+		Sig:       msg.Sig,
+		EditedAt:  msg.EditedAt,
+		Deleted:   msg.Deleted,
+		ThreadID:  msg.ThreadID.String(),
+		ExpiresAt: msg.ExpiresAt,
 	}
-	return m.store.UpdateMessage(tmsg)
-}
-func (m MessageRepo) GetByID(id entity.ID) (entity.Message, error) {
-	bhmsg, err := m.store.MsgByID(id.String())
-	if err != nil {
-		return entity.Message{}, err
+	if r := msg.ReplyTo; r != nil {
+		tmsg.ReplyTo = &store.BHReply{ID: r.ID.String(), Author: store.BHContact{ID: r.Author.ID.String(), Name: r.Author.Name}, Snippet: r.Snippet}
+	}
+	for _, a := range msg.Attachments {
+		tmsg.Attachments = append(tmsg.Attachments, store.BHAttachment{Hash: a.Hash, Name: a.Name, Mime: a.Mime, Size: a.Size})
 	}
+	return tmsg
+}
+
+func fromBHMessage(m store.BHTextMessage) entity.Message {
 	msg := entity.Message{
-		ID:        entity.ID(bhmsg.ID),
-		ChatID:    entity.ID(bhmsg.ChatID),
-		CreatedAt: bhmsg.CreatedAt,
-		Text:      bhmsg.Text,
-		Status:    entity.Status(bhmsg.Status),
+		ID:        entity.ID(m.ID),
+		ChatID:    entity.ID(m.ChatID),
+		CreatedAt: m.CreatedAt,
+		Text:      m.Text,
+		Status:    entity.Status(m.Status),
 		Author: entity.Contact{
-			ID:   entity.ID(bhmsg.Author.ID),
-			Name: bhmsg.Author.Name,
+			ID:   entity.ID(m.Author.ID),
+			Name: m.Author.Name,
 		},
+		Sig:       m.Sig,
+		EditedAt:  m.EditedAt,
+		Deleted:   m.Deleted,
+		ThreadID:  entity.ID(m.ThreadID),
+		ExpiresAt: m.ExpiresAt,
 	}
-	return msg, nil
-}
-func (m MessageRepo) GetAll(opt IOption) ([]entity.Message, error) {
-	messages := make([]entity.Message, 0)
-	chID, pres := opt.Filters()["ChatID"].(string)
-	if !pres {
-		return nil, ErrNotSupported
-	}
-	status, _ := opt.Filters()["Status"].([]entity.Status)
-	bhm, err := m.store.ChatMessages(string(chID), opt.Skip(), opt.Limit(), status...)
-	if err != nil {
-		return nil, err
+	if r := m.ReplyTo; r != nil {
+		msg.ReplyTo = &entity.Reply{ID: entity.ID(r.ID), Author: entity.Contact{ID: entity.ID(r.Author.ID), Name: r.Author.Name}, Snippet: r.Snippet}
 	}
-	for _, m := range bhm {
-		messages = append(messages, entity.Message{
-			ID:        entity.ID(m.ID),
-			ChatID:    entity.ID(m.ChatID),
-			CreatedAt: m.CreatedAt,
-			Text:      m.Text,
-			Status:    entity.Status(m.Status),
-			Author: entity.Contact{
-				ID:   entity.ID(m.Author.ID),
-				Name: m.Author.Name,
-			},
-		})
+	msg.Reactions = countReactions(m.Reactions)
+	for _, a := range m.Attachments {
+		msg.Attachments = append(msg.Attachments, entity.Attachment{Hash: a.Hash, Name: a.Name, Mime: a.Mime, Size: a.Size})
 	}
-	return messages, nil
+	return msg
 }
 
 func (m MessageRepo) Get() (entity.Message, error) {
@@ -297,11 +378,16 @@ func (c ContactRepo) GetByID(id entity.ID) (entity.Contact, error) {
 		Name: con.Name,
 	}, nil
 }
-func (c ContactRepo) GetAll(opt IOption) ([]entity.Contact, error) {
+// GetAll returns a page of the contacts.
+func (c ContactRepo) GetAll(page entity.Page, filter Filter) ([]entity.Contact, entity.PageInfo, error) {
 	cons := make([]entity.Contact, 0)
-	bhcl, err := c.store.AllContacts(opt.Skip(), opt.Limit())
+	p, err := seek(page)
 	if err != nil {
-		return nil, err
+		return nil, entity.PageInfo{}, err
+	}
+	bhcl, more, err := c.store.AllContacts(p)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
 	}
 	for _, val := range bhcl {
 		cons = append(cons, entity.Contact{
@@ -309,7 +395,7 @@ func (c ContactRepo) GetAll(opt IOption) ([]entity.Contact, error) {
 			ID:   entity.ID(val.ID),
 		})
 	}
-	return cons, nil
+	return cons, pageInfo(cons, more, contactCursor), nil
 }
 
 func (c ContactRepo) Get() (entity.Contact, error) {
@@ -343,16 +429,16 @@ func (i IdentityRepo) Put(iden entity.Identity) error {
 func (i IdentityRepo) GetByID(id entity.ID) (entity.Identity, error) {
 	return entity.Identity{}, ErrNotImplemented
 }
-func (i IdentityRepo) GetAll(_ IOption) ([]entity.Identity, error) {
+func (i IdentityRepo) GetAll(_ entity.Page, _ Filter) ([]entity.Identity, entity.PageInfo, error) {
 	id, err := i.store.GetIdentity()
 	if err != nil {
-		return nil, err
+		return nil, entity.PageInfo{}, err
 	}
 	return []entity.Identity{{
 		ID:      entity.ID(id.ID),
 		Name:    id.Name,
 		PrivKey: id.Key,
-	}}, nil
+	}}, entity.PageInfo{}, nil
 }
 
 func (i IdentityRepo) Get() (entity.Identity, error) {
@@ -366,3 +452,203 @@ func (i IdentityRepo) Get() (entity.Identity, error) {
 		PrivKey: id.Key,
 	}, nil
 }
+
+func fromBHContacts(cs []store.BHContact) []entity.Contact {
+	res := make([]entity.Contact, 0, len(cs))
+	for _, c := range cs {
+		res = append(res, entity.Contact{ID: entity.ID(c.ID), Name: c.Name})
+	}
+	return res
+}
+
+func fromBHChat(ct store.BHChat) entity.ChatInfo {
+	ci := entity.ChatInfo{
+		ID:         entity.ID(ct.ID),
+		Name:       ct.Name,
+		Members:    fromBHContacts(ct.Members),
+		Type:       ct.Type,
+		Admins:     fromBHContacts(ct.Admins),
+		Expiry:     ct.Expiry,
+		Unread:     ct.Summary.Unread,
+		LatestText: ct.Summary.LastText,
+		LatestAt:   ct.Summary.LastAt,
+	}
+	if ct.Summary.LastMsgID != "" {
+		ci.LatestID = entity.ID(ct.Summary.LastMsgID)
+		ci.LatestAuthor = &entity.Contact{ID: entity.ID(ct.Summary.LastAuthor.ID), Name: ct.Summary.LastAuthor.Name}
+	}
+	return ci
+}
+
+// ChatRequestRepo keeps received group invites until they are answered.
+type ChatRequestRepo struct {
+	store *store.Store
+}
+
+func NewChatRequestRepo(store *store.Store) ChatRequestRepo {
+	return ChatRequestRepo{store: store}
+}
+
+func toBHChatRequest(r entity.ChatRequest) store.BHChatRequest {
+	return store.BHChatRequest{
+		ID:        r.Chat.ID.String(),
+		Chat:      toBHChat(r.Chat),
+		From:      store.BHContact{ID: r.From.ID.String(), Name: r.From.Name},
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func fromBHChatRequest(r store.BHChatRequest) entity.ChatRequest {
+	return entity.ChatRequest{
+		Chat:      fromBHChat(r.Chat),
+		From:      entity.Contact{ID: entity.ID(r.From.ID), Name: r.From.Name},
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func (c ChatRequestRepo) Add(r entity.ChatRequest) error {
+	return c.store.PutChatRequest(toBHChatRequest(r))
+}
+
+func (c ChatRequestRepo) Put(r entity.ChatRequest) error {
+	return c.store.PutChatRequest(toBHChatRequest(r))
+}
+
+// GetByID returns the request to join chat id.
+func (c ChatRequestRepo) GetByID(id entity.ID) (entity.ChatRequest, error) {
+	r, err := c.store.ChatRequestByID(id.String())
+	if err != nil {
+		return entity.ChatRequest{}, err
+	}
+	return fromBHChatRequest(r), nil
+}
+
+// GetAll returns a page of the requests, the newest first.
+func (c ChatRequestRepo) GetAll(page entity.Page, filter Filter) ([]entity.ChatRequest, entity.PageInfo, error) {
+	p, err := seek(page)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	bhr, more, err := c.store.ChatRequests(p)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	res := make([]entity.ChatRequest, 0, len(bhr))
+	for _, r := range bhr {
+		res = append(res, fromBHChatRequest(r))
+	}
+	return res, pageInfo(res, more, requestCursor), nil
+}
+
+func (c ChatRequestRepo) Get() (entity.ChatRequest, error) {
+	return entity.ChatRequest{}, ErrNotSupported
+}
+
+func (c ChatRequestRepo) Delete(id entity.ID) error {
+	return c.store.DeleteChatRequest(id.String())
+}
+
+// InvitationRepo tracks sent group invites, an invitation is identified by
+// the chat and the invited contact.
+type InvitationRepo struct {
+	store *store.Store
+}
+
+func NewInvitationRepo(store *store.Store) InvitationRepo {
+	return InvitationRepo{store: store}
+}
+
+func invitationKey(chatID entity.ID, to entity.ID) string {
+	return chatID.String() + "/" + to.String()
+}
+
+func (i InvitationRepo) Add(inv entity.Invitation) error {
+	return i.Put(inv)
+}
+
+func (i InvitationRepo) Put(inv entity.Invitation) error {
+	return i.store.PutInvitation(store.BHInvitation{
+		Key:    invitationKey(inv.ChatID, inv.To.ID),
+		ChatID: inv.ChatID.String(),
+		To:     store.BHContact{ID: inv.To.ID.String(), Name: inv.To.Name},
+		Status: inv.Status,
+	})
+}
+
+// Find returns the invitation of chatID sent to contact to.
+func (i InvitationRepo) Find(chatID entity.ID, to entity.ID) (entity.Invitation, error) {
+	inv, err := i.store.InvitationByKey(invitationKey(chatID, to))
+	if err != nil {
+		return entity.Invitation{}, err
+	}
+	return fromBHInvitation(inv), nil
+}
+
+func fromBHInvitation(inv store.BHInvitation) entity.Invitation {
+	return entity.Invitation{
+		ChatID: entity.ID(inv.ChatID),
+		To:     entity.Contact{ID: entity.ID(inv.To.ID), Name: inv.To.Name},
+		Status: inv.Status,
+	}
+}
+
+func (i InvitationRepo) GetByID(id entity.ID) (entity.Invitation, error) {
+	return entity.Invitation{}, ErrNotSupported
+}
+
+// GetAll returns the invitations of the chat set by the ChatID filter, a
+// chat has few of them and they are not paged.
+func (i InvitationRepo) GetAll(page entity.Page, filter Filter) ([]entity.Invitation, entity.PageInfo, error) {
+	chID, pres := filter["ChatID"].(string)
+	if !pres {
+		return nil, entity.PageInfo{}, ErrNotSupported
+	}
+	bhi, err := i.store.ChatInvitations(chID)
+	if err != nil {
+		return nil, entity.PageInfo{}, err
+	}
+	res := make([]entity.Invitation, 0, len(bhi))
+	for _, inv := range bhi {
+		res = append(res, fromBHInvitation(inv))
+	}
+	return res, entity.PageInfo{}, nil
+}
+
+func (i InvitationRepo) Get() (entity.Invitation, error) {
+	return entity.Invitation{}, ErrNotSupported
+}
+
+// HistoryRepo keeps the earlier texts of edited messages.
+type HistoryRepo struct {
+	store *store.Store
+}
+
+func NewHistoryRepo(s *store.Store) HistoryRepo {
+	return HistoryRepo{store: s}
+}
+
+func (h HistoryRepo) Add(v entity.MessageVersion) error {
+	return h.store.PutMessageVersion(store.BHMessageVersion{
+		Key:       fmt.Sprintf("%s/%d", v.MsgID, v.CreatedAt),
+		MsgID:     v.MsgID.String(),
+		Text:      v.Text,
+		CreatedAt: v.CreatedAt,
+	})
+}
+
+// GetAll returns the versions of a message, the oldest first.
+func (h HistoryRepo) GetAll(msgID entity.ID) ([]entity.MessageVersion, error) {
+	bhs, err := h.store.MessageVersions(msgID.String())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]entity.MessageVersion, 0, len(bhs))
+	for _, v := range bhs {
+		res = append(res, entity.MessageVersion{MsgID: entity.ID(v.MsgID), Text: v.Text, CreatedAt: v.CreatedAt})
+	}
+	return res, nil
+}
+
+func (h HistoryRepo) Delete(msgID entity.ID) error {
+	return h.store.DeleteMessageVersions(msgID.String())
+}
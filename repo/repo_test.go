@@ -1,6 +1,7 @@
 package repo_test
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -38,8 +39,7 @@ func TestContact(t *testing.T) {
 		err := rc.Add(val)
 		require.NoError(t, err)
 	}
-	opt := repo.NewOption(0, 50)
-	res2, err := rc.GetAll(opt)
+	res2, _, err := rc.GetAll(entity.Page{Limit: 50}, nil)
 	require.NoError(t, err)
 	if !reflect.DeepEqual(res2, test_contact) {
 		t.Error("in and out are not equal")
@@ -172,11 +172,14 @@ func TestChat(t *testing.T) {
 	rmsg = repo.NewMessageRepo(s)
 	rmsg.Add(chat1[0])
 	rmsg.Add(chat1[1])
-	b := repo.NewOption(0, 50)
-	res, err := chrepo.GetAll(b)
+	// chats tell their latest message and the latest active comes first
+	chatinfo0.LatestID, chatinfo0.LatestAuthor, chatinfo0.LatestAt = chat0[1].ID, &chat0[1].Author, chat0[1].CreatedAt
+	chatinfo1.LatestID, chatinfo1.LatestAuthor, chatinfo1.LatestAt = chat1[0].ID, &chat1[0].Author, chat1[0].CreatedAt
+	b := entity.Page{Limit: 50}
+	res, _, err := chrepo.GetAll(b, nil)
 	require.NoError(t, err)
 
-	if !reflect.DeepEqual(res, []entity.ChatInfo{chatinfo0, chatinfo1}) {
+	if !reflect.DeepEqual(res, []entity.ChatInfo{chatinfo1, chatinfo0}) {
 		t.Error("in and out are not equal")
 	}
 
@@ -187,19 +190,16 @@ func TestChat(t *testing.T) {
 	if !reflect.DeepEqual(res2, chatinfo0) {
 		t.Error("in and out are not equal")
 	}
-	b.AddFilter("ChatID", string(res2.ID))
-	res_msg, err := rmsg.GetAll(b)
+	f := repo.Filter{"ChatID": string(res2.ID)}
+	res_msg, _, err := rmsg.GetAll(b, f)
 	require.NoError(t, err)
 	require.Equal(t, len(res_msg), len(chat1))
-	res_msg, err = rmsg.GetAll(b)
+	res_msg, _, err = rmsg.GetAll(b, f)
 	require.NoError(t, err)
 	require.Equal(t, len(res_msg), len(chat0))
 
 	// Test Status filter
-	opt := repo.NewOption(0, 50)
-	opt.AddFilter("ChatID", "1")
-	opt.AddFilter("Status",[]entity.Status{entity.Received})
-	msgs, err := rmsg.GetAll(opt)
+	msgs, _, err := rmsg.GetAll(entity.Page{Limit: 50}, repo.Filter{"ChatID": "1", "Status": []entity.Status{entity.Received}})
 	require.NoError(t, err)
 	require.Equal(t, 1, len(msgs))
 
@@ -224,3 +224,55 @@ func TestIdentity(t *testing.T) {
 	t.Log(id)
 
 }
+
+func TestMessagePages(t *testing.T) {
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	rmsg := repo.NewMessageRepo(s)
+
+	// 5 and 4 are sent in the same second, the id orders them
+	times := []int64{100, 200, 300, 400, 400}
+	for i, at := range times {
+		err := rmsg.Add(entity.Message{ID: entity.ID(fmt.Sprint(i + 1)), ChatID: "1", CreatedAt: at, Text: "hi"})
+		require.NoError(t, err)
+	}
+	page := func(p entity.Page) ([]entity.ID, entity.PageInfo) {
+		msgs, info, err := rmsg.GetAll(p, repo.Filter{"ChatID": "1"})
+		require.NoError(t, err)
+		ids := make([]entity.ID, 0)
+		for _, m := range msgs {
+			ids = append(ids, m.ID)
+		}
+		return ids, info
+	}
+
+	ids, first := page(entity.Page{Limit: 2})
+	require.Equal(t, []entity.ID{"5", "4"}, ids)
+	require.True(t, first.More)
+	ids, second := page(entity.Page{After: first.End, Limit: 2})
+	require.Equal(t, []entity.ID{"3", "2"}, ids)
+	require.True(t, second.More)
+	ids, last := page(entity.Page{After: second.End, Limit: 2})
+	require.Equal(t, []entity.ID{"1"}, ids)
+	require.False(t, last.More)
+
+	// a new message does not shift the pages that were read
+	err = rmsg.Add(entity.Message{ID: "6", ChatID: "1", CreatedAt: 500, Text: "hi"})
+	require.NoError(t, err)
+	ids, _ = page(entity.Page{After: first.End, Limit: 2})
+	require.Equal(t, []entity.ID{"3", "2"}, ids)
+	// and is read before the first page
+	ids, newer := page(entity.Page{Before: first.Start, Limit: 2})
+	require.Equal(t, []entity.ID{"6"}, ids)
+	require.False(t, newer.More)
+	ids, info := page(entity.Page{Before: last.Start, Limit: 3})
+	require.Equal(t, []entity.ID{"4", "3", "2"}, ids)
+	require.True(t, info.More)
+	ids, _ = page(entity.Page{Before: last.Start})
+	require.Equal(t, []entity.ID{"6", "5", "4", "3", "2"}, ids)
+
+	_, _, err = rmsg.GetAll(entity.Page{After: "nope"}, repo.Filter{"ChatID": "1"})
+	require.ErrorIs(t, err, entity.ErrInvalidCursor)
+	_, _, err = rmsg.GetAll(entity.Page{After: first.End, Before: first.Start}, repo.Filter{"ChatID": "1"})
+	require.ErrorIs(t, err, entity.ErrInvalidCursor)
+}
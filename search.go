@@ -0,0 +1,92 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/hood-chat/core/entity"
+	rp "github.com/hood-chat/core/repo"
+	st "github.com/hood-chat/core/store"
+)
+
+var ErrEmptyQuery = errors.New("search query has no words")
+
+// text kept before the first match of a snippet and the longest snippet,
+// in runes
+const (
+	snippetContext = 30
+	snippetMax     = 120
+)
+
+// Search finds a page of the messages with all words of query, the best
+// matches first. Words match whole and case is ignored.
+func (c *Chat) Search(query string, opt SearchMessageOpt, page entity.Page) (entity.SearchResultPage, error) {
+	words := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, t := range st.Tokenize(query) {
+		if !words[t.Term] {
+			words[t.Term] = true
+			terms = append(terms, t.Term)
+		}
+	}
+	if len(terms) == 0 {
+		return entity.SearchResultPage{}, ErrEmptyQuery
+	}
+	filter := rp.Filter{}
+	if opt.ChatID != nil {
+		filter["ChatID"] = *opt.ChatID
+	}
+	if len(opt.Authors) > 0 {
+		filter["Authors"] = opt.Authors
+	}
+	if opt.Since != nil {
+		filter["Since"] = *opt.Since
+	}
+	if opt.Until != nil {
+		filter["Until"] = *opt.Until
+	}
+	res, info, err := c.mRepo.Search(terms, page, filter)
+	if err != nil {
+		return entity.SearchResultPage{}, err
+	}
+	for i := range res {
+		res[i].Snippet, res[i].Highlights = highlight(res[i].Message.Text, words)
+	}
+	return entity.SearchResultPage{Items: res, PageInfo: info}, nil
+}
+
+// highlight cuts text around the first of words and returns where the
+// words are in the cut.
+func highlight(text string, words map[string]bool) (string, []entity.Highlight) {
+	var matches []st.Token
+	for _, t := range st.Tokenize(text) {
+		if words[t.Term] {
+			matches = append(matches, t)
+		}
+	}
+	runes := []rune(text)
+	start := 0
+	if len(matches) > 0 && matches[0].Start > snippetContext {
+		start = matches[0].Start - snippetContext
+	}
+	end := len(runes)
+	if end-start > snippetMax {
+		end = start + snippetMax
+	}
+	snippet := string(runes[start:end])
+	offset := -start
+	if start > 0 {
+		snippet = "…" + snippet
+		offset++
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	var hl []entity.Highlight
+	for _, m := range matches {
+		if m.End > end {
+			break
+		}
+		hl = append(hl, entity.Highlight{Start: m.Start + offset, End: m.End + offset})
+	}
+	return snippet, hl
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	pb "github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/crypto/curve25519"
+)
+
+var ErrUnsupportedKey = errors.New("only Ed25519 identities are supported")
+
+// curveP is the field prime 2^255 - 19 shared by Ed25519 and X25519.
+var curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// KeyPair is an X25519 key pair.
+type KeyPair struct {
+	Priv []byte `json:"priv"`
+	Pub  []byte `json:"pub"`
+}
+
+func newKeyPair() (KeyPair, error) {
+	priv := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(priv); err != nil {
+		return KeyPair{}, err
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	return KeyPair{priv, pub}, nil
+}
+
+func dh(priv []byte, pub []byte) ([]byte, error) {
+	return curve25519.X25519(priv, pub)
+}
+
+// identityKeyPair converts a libp2p Ed25519 private key to the equivalent
+// X25519 key pair, the same way libsodium's crypto_sign_ed25519_sk_to_curve25519 does.
+func identityKeyPair(sk crypto.PrivKey) (KeyPair, error) {
+	if sk.Type() != pb.KeyType_Ed25519 {
+		return KeyPair{}, ErrUnsupportedKey
+	}
+	raw, err := sk.Raw()
+	if err != nil {
+		return KeyPair{}, err
+	}
+	h := sha512.Sum512(raw[:ed25519.SeedSize])
+	priv := h[:curve25519.ScalarSize]
+	priv[0] &= 248
+	priv[31] &= 127
+	priv[31] |= 64
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	return KeyPair{priv, pub}, nil
+}
+
+// identityPublicKey returns the X25519 public key of a peer with an Ed25519 identity.
+func identityPublicKey(p peer.ID) ([]byte, error) {
+	pk, err := p.ExtractPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if pk.Type() != pb.KeyType_Ed25519 {
+		return nil, ErrUnsupportedKey
+	}
+	raw, err := pk.Raw()
+	if err != nil {
+		return nil, err
+	}
+	return edwardsToMontgomery(raw)
+}
+
+// edwardsToMontgomery maps an Ed25519 public key y to the X25519 u
+// coordinate with u = (1 + y) / (1 - y).
+func edwardsToMontgomery(pub []byte) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrUnsupportedKey
+	}
+	be := make([]byte, len(pub))
+	for i, b := range pub {
+		be[len(pub)-1-i] = b
+	}
+	// the top bit holds the sign of x
+	be[0] &= 0x7f
+	y := new(big.Int).SetBytes(be)
+
+	one := big.NewInt(1)
+	num := new(big.Int).Add(one, y)
+	den := new(big.Int).Sub(one, y)
+	den.Mod(den, curveP)
+	if den.Sign() == 0 {
+		return nil, ErrUnsupportedKey
+	}
+	den.ModInverse(den, curveP)
+	u := num.Mul(num, den)
+	u.Mod(u, curveP)
+
+	out := make([]byte, curve25519.PointSize)
+	ub := u.Bytes()
+	for i, b := range ub {
+		out[len(ub)-1-i] = b
+	}
+	return out, nil
+}
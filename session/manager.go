@@ -0,0 +1,250 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/hood-chat/core/pb"
+	"github.com/hood-chat/core/store"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"golang.org/x/crypto/hkdf"
+)
+
+var log = logging.Logger("msgr-core-session")
+
+// maxSessions is how many sessions are kept per peer, older ones are only
+// needed for messages that were in flight while both peers started a session.
+const maxSessions = 3
+
+// stateVersion starts a sealed session record, records written before they
+// were sealed are plain JSON.
+const stateVersion = 1
+
+var stateInfo = []byte("hood-chat session state")
+
+var ErrSealedState = errors.New("can not open session state")
+
+// record holds the sessions with one peer, the active one first.
+type record struct {
+	Sessions []*State `json:"sessions"`
+}
+
+// Manager encrypts and decrypts messages with per contact double ratchet
+// sessions persisted in the store. The sessions hold every key of the
+// conversations, they are sealed with a key derived from the identity.
+type Manager struct {
+	store *store.Store
+	self  peer.ID
+	ik    KeyPair
+	// seals the stored sessions
+	aead cipher.AEAD
+	mux  sync.Mutex
+}
+
+func NewManager(s *store.Store, sk crypto.PrivKey) (*Manager, error) {
+	ik, err := identityKeyPair(sk)
+	if err != nil {
+		return nil, err
+	}
+	self, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := stateCipher(sk)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{store: s, self: self, ik: ik, aead: aead}, nil
+}
+
+// stateCipher derives the key of the stored sessions from the identity key,
+// they are only readable once the identity is unlocked.
+func stateCipher(sk crypto.PrivKey) (cipher.AEAD, error) {
+	raw, err := sk.Raw()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	_, err = io.ReadFull(hkdf.New(sha256.New, raw, nil, stateInfo), key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext for peer to, starting a new session when there is none.
+func (m *Manager) Encrypt(to peer.ID, plaintext []byte) (*pb.SecureMessage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	rec, err := m.load(to)
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.Sessions) == 0 {
+		remoteIK, err := identityPublicKey(to)
+		if err != nil {
+			return nil, err
+		}
+		st, err := initiate(m.ik, remoteIK)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("new session with %s", to)
+		rec.Sessions = []*State{st}
+	}
+	h, ct, err := rec.Sessions[0].Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	err = m.save(to, rec)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SecureMessage{
+		Ephemeral:  h.Ephemeral,
+		Dh:         h.DH,
+		Pn:         h.PN,
+		N:          h.N,
+		Ciphertext: ct,
+	}, nil
+}
+
+// Decrypt opens a message from peer from.
+func (m *Manager) Decrypt(from peer.ID, msg *pb.SecureMessage) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	rec, err := m.load(from)
+	if err != nil {
+		return nil, err
+	}
+	h := Header{Ephemeral: msg.GetEphemeral(), DH: msg.GetDh(), PN: msg.GetPn(), N: msg.GetN()}
+
+	if len(h.Ephemeral) > 0 {
+		for _, st := range rec.Sessions {
+			if !st.Initiator && string(st.Ephemeral) == string(h.Ephemeral) {
+				return m.decrypt(from, rec, st, h, msg.GetCiphertext(), false)
+			}
+		}
+		remoteIK, err := identityPublicKey(from)
+		if err != nil {
+			return nil, err
+		}
+		st, err := respond(m.ik, remoteIK, h.Ephemeral)
+		if err != nil {
+			return nil, err
+		}
+		pt, err := st.Decrypt(h, msg.GetCiphertext())
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("session started by %s", from)
+		m.add(from, rec, st)
+		return pt, m.save(from, rec)
+	}
+
+	for _, st := range rec.Sessions {
+		pt, err := m.decrypt(from, rec, st, h, msg.GetCiphertext(), true)
+		if err == nil {
+			return pt, nil
+		}
+	}
+	return nil, ErrDecrypt
+}
+
+// decrypt tries st on a copy and stores the advanced state on success.
+// With promote the session becomes the active one, the peer is using it.
+func (m *Manager) decrypt(from peer.ID, rec *record, st *State, h Header, ct []byte, promote bool) ([]byte, error) {
+	c := st.Clone()
+	pt, err := c.Decrypt(h, ct)
+	if err != nil {
+		return nil, err
+	}
+	*st = *c
+	if promote {
+		sessions := []*State{st}
+		for _, s := range rec.Sessions {
+			if s != st {
+				sessions = append(sessions, s)
+			}
+		}
+		rec.Sessions = sessions
+	}
+	return pt, m.save(from, rec)
+}
+
+// add stores a new responder session. When both peers started a session at
+// the same time the one initiated by the smaller peer ID stays active, so
+// both ends settle on the same session.
+func (m *Manager) add(from peer.ID, rec *record, st *State) {
+	if len(rec.Sessions) > 0 {
+		active := rec.Sessions[0]
+		if active.Initiator && !active.Confirmed && m.self < from {
+			rec.Sessions = append([]*State{active, st}, rec.Sessions[1:]...)
+			m.trim(rec)
+			return
+		}
+	}
+	rec.Sessions = append([]*State{st}, rec.Sessions...)
+	m.trim(rec)
+}
+
+func (m *Manager) trim(rec *record) {
+	if len(rec.Sessions) > maxSessions {
+		rec.Sessions = rec.Sessions[:maxSessions]
+	}
+}
+
+func (m *Manager) load(p peer.ID) (*record, error) {
+	rec := new(record)
+	bs, err := m.store.SessionByPeer(p.String())
+	if errors.Is(err, store.ErrNotFound) {
+		return rec, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := bs.State
+	if len(b) > 0 && b[0] == stateVersion {
+		ns := m.aead.NonceSize()
+		if len(b) < 1+ns {
+			return nil, ErrSealedState
+		}
+		b, err = m.aead.Open(nil, b[1:1+ns], b[1+ns:], []byte(p))
+		if err != nil {
+			return nil, ErrSealedState
+		}
+	}
+	err = json.Unmarshal(b, rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// save seals rec for the store, the version byte and nonce come first.
+func (m *Manager) save(p peer.ID, rec *record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, m.aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	sealed := append([]byte{stateVersion}, nonce...)
+	sealed = m.aead.Seal(sealed, nonce, b, []byte(p))
+	return m.store.PutSession(store.BHSession{PeerID: p.String(), State: sealed})
+}
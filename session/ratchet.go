@@ -0,0 +1,299 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// MaxSkip is the most message keys of one chain that are kept for
+// messages arriving out of order.
+const MaxSkip = 1000
+
+// maxSkipped bounds the skipped keys kept per session.
+const maxSkipped = 2 * MaxSkip
+
+var ErrDecrypt = errors.New("can not decrypt message")
+var ErrTooManySkipped = errors.New("too many skipped messages")
+
+var (
+	x3dhInfo    = []byte("hood-chat x3dh")
+	ratchetInfo = []byte("hood-chat ratchet")
+	messageInfo = []byte("hood-chat message")
+)
+
+// Header is sent in clear next to every ciphertext.
+type Header struct {
+	Ephemeral []byte
+	DH        []byte
+	PN        uint32
+	N         uint32
+}
+
+func (h Header) encode() []byte {
+	b := make([]byte, 0, len(h.Ephemeral)+len(h.DH)+10)
+	b = append(b, byte(len(h.Ephemeral)))
+	b = append(b, h.Ephemeral...)
+	b = append(b, h.DH...)
+	b = binary.BigEndian.AppendUint32(b, h.PN)
+	b = binary.BigEndian.AppendUint32(b, h.N)
+	return b
+}
+
+// State is the double ratchet state of one session with a peer.
+type State struct {
+	// handshake ephemeral key of the initiator, identifies the session
+	Ephemeral []byte `json:"ephemeral"`
+	Initiator bool   `json:"initiator"`
+	// set once a message of this session was received
+	Confirmed bool `json:"confirmed"`
+	// associated data, the identity keys of initiator and responder
+	AD []byte `json:"ad"`
+
+	DHs     KeyPair           `json:"dhs"`
+	DHr     []byte            `json:"dhr"`
+	RK      []byte            `json:"rk"`
+	CKs     []byte            `json:"cks"`
+	CKr     []byte            `json:"ckr"`
+	Ns      uint32            `json:"ns"`
+	Nr      uint32            `json:"nr"`
+	PN      uint32            `json:"pn"`
+	Skipped map[string][]byte `json:"skipped"`
+}
+
+// initiate starts a session with the owner of remoteIK. The returned
+// state can encrypt right away, the remote side builds its state from the
+// ephemeral key sent in the headers.
+func initiate(self KeyPair, remoteIK []byte) (*State, error) {
+	ek, err := newKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	sk, err := x3dh(self.Priv, ek.Priv, remoteIK, remoteIK)
+	if err != nil {
+		return nil, err
+	}
+	st := &State{
+		Ephemeral: ek.Pub,
+		Initiator: true,
+		AD:        append(append([]byte{}, self.Pub...), remoteIK...),
+		DHr:       remoteIK,
+		Skipped:   make(map[string][]byte),
+	}
+	st.DHs, err = newKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	out, err := dh(st.DHs.Priv, st.DHr)
+	if err != nil {
+		return nil, err
+	}
+	st.RK, st.CKs = kdfRK(sk, out)
+	return st, nil
+}
+
+// respond builds the responder side of a session started by the owner of
+// remoteIK with the handshake key ephemeral.
+func respond(self KeyPair, remoteIK []byte, ephemeral []byte) (*State, error) {
+	sk, err := x3dh(self.Priv, self.Priv, remoteIK, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	return &State{
+		Ephemeral: ephemeral,
+		AD:        append(append([]byte{}, remoteIK...), self.Pub...),
+		DHs:       self,
+		RK:        sk,
+		Skipped:   make(map[string][]byte),
+	}, nil
+}
+
+// x3dh derives the shared secret from DH(IKa, IKb) || DH(EKa, IKb). Both
+// sides pass their own private keys and the remote public keys.
+func x3dh(ikPriv []byte, ekPriv []byte, ikPub []byte, ekPub []byte) ([]byte, error) {
+	dh1, err := dh(ikPriv, ikPub)
+	if err != nil {
+		return nil, err
+	}
+	dh2, err := dh(ekPriv, ekPub)
+	if err != nil {
+		return nil, err
+	}
+	ikm := append(dh1, dh2...)
+	sk := make([]byte, 32)
+	_, err = io.ReadFull(hkdf.New(sha256.New, ikm, make([]byte, 32), x3dhInfo), sk)
+	return sk, err
+}
+
+// Encrypt advances the sending chain and seals plaintext.
+func (st *State) Encrypt(plaintext []byte) (Header, []byte, error) {
+	var mk []byte
+	st.CKs, mk = kdfCK(st.CKs)
+	h := Header{DH: st.DHs.Pub, PN: st.PN, N: st.Ns}
+	if st.Initiator && !st.Confirmed {
+		h.Ephemeral = st.Ephemeral
+	}
+	st.Ns++
+	ct, err := seal(mk, plaintext, append(append([]byte{}, st.AD...), h.encode()...))
+	return h, ct, err
+}
+
+// Decrypt opens a ciphertext. On error the state may be partially
+// advanced, callers should decrypt on a Clone and keep it only on success.
+func (st *State) Decrypt(h Header, ciphertext []byte) ([]byte, error) {
+	ad := append(append([]byte{}, st.AD...), h.encode()...)
+	key := skippedKey(h.DH, h.N)
+	if mk, ok := st.Skipped[key]; ok {
+		pt, err := open(mk, ciphertext, ad)
+		if err != nil {
+			return nil, err
+		}
+		delete(st.Skipped, key)
+		st.Confirmed = true
+		return pt, nil
+	}
+	if !hmac.Equal(h.DH, st.DHr) {
+		err := st.skip(h.PN)
+		if err != nil {
+			return nil, err
+		}
+		err = st.ratchet(h.DH)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := st.skip(h.N)
+	if err != nil {
+		return nil, err
+	}
+	var mk []byte
+	st.CKr, mk = kdfCK(st.CKr)
+	st.Nr++
+	pt, err := open(mk, ciphertext, ad)
+	if err != nil {
+		return nil, err
+	}
+	st.Confirmed = true
+	return pt, nil
+}
+
+func (st *State) skip(until uint32) error {
+	if st.CKr == nil {
+		return nil
+	}
+	if until > st.Nr {
+		n := int(until - st.Nr)
+		if n > MaxSkip || len(st.Skipped)+n > maxSkipped {
+			return ErrTooManySkipped
+		}
+	}
+	for st.Nr < until {
+		var mk []byte
+		st.CKr, mk = kdfCK(st.CKr)
+		st.Skipped[skippedKey(st.DHr, st.Nr)] = mk
+		st.Nr++
+	}
+	return nil
+}
+
+func (st *State) ratchet(remote []byte) error {
+	st.PN = st.Ns
+	st.Ns = 0
+	st.Nr = 0
+	st.DHr = remote
+	out, err := dh(st.DHs.Priv, st.DHr)
+	if err != nil {
+		return err
+	}
+	st.RK, st.CKr = kdfRK(st.RK, out)
+	st.DHs, err = newKeyPair()
+	if err != nil {
+		return err
+	}
+	out, err = dh(st.DHs.Priv, st.DHr)
+	if err != nil {
+		return err
+	}
+	st.RK, st.CKs = kdfRK(st.RK, out)
+	return nil
+}
+
+// Clone returns a deep copy of the state.
+func (st *State) Clone() *State {
+	b, err := json.Marshal(st)
+	if err != nil {
+		panic(fmt.Sprintf("session: can not copy state: %s", err))
+	}
+	c := new(State)
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		panic(fmt.Sprintf("session: can not copy state: %s", err))
+	}
+	return c
+}
+
+func skippedKey(dh []byte, n uint32) string {
+	return fmt.Sprintf("%s:%d", hex.EncodeToString(dh), n)
+}
+
+func kdfRK(rk []byte, dhOut []byte) ([]byte, []byte) {
+	out := make([]byte, 64)
+	_, err := io.ReadFull(hkdf.New(sha256.New, dhOut, rk, ratchetInfo), out)
+	if err != nil {
+		panic(err)
+	}
+	return out[:32], out[32:]
+}
+
+func kdfCK(ck []byte) ([]byte, []byte) {
+	m := hmac.New(sha256.New, ck)
+	m.Write([]byte{0x02})
+	next := m.Sum(nil)
+	m = hmac.New(sha256.New, ck)
+	m.Write([]byte{0x01})
+	mk := m.Sum(nil)
+	return next, mk
+}
+
+func messageCipher(mk []byte) (cipher.AEAD, []byte, error) {
+	out := make([]byte, 32+12)
+	_, err := io.ReadFull(hkdf.New(sha256.New, mk, make([]byte, 32), messageInfo), out)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(out[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, out[32:], err
+}
+
+func seal(mk []byte, plaintext []byte, ad []byte) ([]byte, error) {
+	aead, nonce, err := messageCipher(mk)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, ad), nil
+}
+
+func open(mk []byte, ciphertext []byte, ad []byte) ([]byte, error) {
+	aead, nonce, err := messageCipher(mk)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return pt, nil
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hood-chat/core/pb"
+	"github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
+)
+
+type party struct {
+	id  peer.ID
+	sk  crypto.PrivKey
+	s   *store.Store
+	mgr *Manager
+}
+
+func newParty(t *testing.T) *party {
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	mgr, err := NewManager(s, sk)
+	require.NoError(t, err)
+	return &party{id, sk, s, mgr}
+}
+
+// restart simulates an app restart, sessions are loaded from the store.
+func (p *party) restart(t *testing.T) {
+	mgr, err := NewManager(p.s, p.sk)
+	require.NoError(t, err)
+	p.mgr = mgr
+}
+
+func send(t *testing.T, from *party, to *party, text string) *pb.SecureMessage {
+	msg, err := from.mgr.Encrypt(to.id, []byte(text))
+	require.NoError(t, err)
+	if len(text) > 4 {
+		// short texts may show up in random bytes
+		require.NotContains(t, string(msg.Ciphertext), text)
+	}
+	return msg
+}
+
+func receive(t *testing.T, to *party, from *party, msg *pb.SecureMessage, text string) {
+	pt, err := to.mgr.Decrypt(from.id, msg)
+	require.NoError(t, err)
+	require.Equal(t, text, string(pt))
+}
+
+func TestIdentityKeyConversion(t *testing.T) {
+	p := newParty(t)
+	kp, err := identityKeyPair(p.sk)
+	require.NoError(t, err)
+	pub, err := identityPublicKey(p.id)
+	require.NoError(t, err)
+	require.Equal(t, kp.Pub, pub)
+	expected, err := curve25519.X25519(kp.Priv, curve25519.Basepoint)
+	require.NoError(t, err)
+	require.Equal(t, expected, pub)
+}
+
+func TestConversation(t *testing.T) {
+	alice, bob := newParty(t), newParty(t)
+
+	m1 := send(t, alice, bob, "hi bob")
+	require.NotEmpty(t, m1.Ephemeral)
+	m2 := send(t, alice, bob, "are you there?")
+	receive(t, bob, alice, m1, "hi bob")
+	receive(t, bob, alice, m2, "are you there?")
+
+	// replay is rejected
+	_, err := bob.mgr.Decrypt(alice.id, m1)
+	require.Error(t, err)
+
+	m3 := send(t, bob, alice, "hi alice")
+	require.Empty(t, m3.Ephemeral)
+	receive(t, alice, bob, m3, "hi alice")
+
+	// handshake key is dropped once alice heard back
+	m4 := send(t, alice, bob, "good")
+	require.Empty(t, m4.Ephemeral)
+	receive(t, bob, alice, m4, "good")
+
+	// state survives restarts
+	alice.restart(t)
+	bob.restart(t)
+	for i := 0; i < 3; i++ {
+		text := fmt.Sprintf("after restart %d", i)
+		receive(t, bob, alice, send(t, alice, bob, text), text)
+		receive(t, alice, bob, send(t, bob, alice, text), text)
+	}
+}
+
+func TestOutOfOrder(t *testing.T) {
+	alice, bob := newParty(t), newParty(t)
+	receive(t, bob, alice, send(t, alice, bob, "hello"), "hello")
+	receive(t, alice, bob, send(t, bob, alice, "hello"), "hello")
+
+	msgs := []*pb.SecureMessage{}
+	for i := 0; i < 5; i++ {
+		msgs = append(msgs, send(t, alice, bob, fmt.Sprint(i)))
+	}
+	reply := send(t, bob, alice, "reply")
+	receive(t, alice, bob, reply, "reply")
+	late := send(t, alice, bob, "new chain")
+
+	receive(t, bob, alice, late, "new chain")
+	for _, i := range []int{3, 0, 4, 2, 1} {
+		receive(t, bob, alice, msgs[i], fmt.Sprint(i))
+	}
+}
+
+func TestTamperedMessage(t *testing.T) {
+	alice, bob := newParty(t), newParty(t)
+	msg := send(t, alice, bob, "hello")
+	msg.Ciphertext[0] ^= 1
+	_, err := bob.mgr.Decrypt(alice.id, msg)
+	require.Error(t, err)
+	msg.Ciphertext[0] ^= 1
+	msg.N = 1
+	_, err = bob.mgr.Decrypt(alice.id, msg)
+	require.Error(t, err)
+
+	// a third party can not read it
+	eve := newParty(t)
+	msg.N = 0
+	_, err = eve.mgr.Decrypt(alice.id, msg)
+	require.Error(t, err)
+	receive(t, bob, alice, msg, "hello")
+}
+
+func TestSimultaneousStart(t *testing.T) {
+	alice, bob := newParty(t), newParty(t)
+
+	a1 := send(t, alice, bob, "from alice")
+	b1 := send(t, bob, alice, "from bob")
+	receive(t, alice, bob, b1, "from bob")
+	receive(t, bob, alice, a1, "from alice")
+
+	// both ends settle on one session
+	for i := 0; i < 3; i++ {
+		text := fmt.Sprint(i)
+		receive(t, bob, alice, send(t, alice, bob, text), text)
+		receive(t, alice, bob, send(t, bob, alice, text), text)
+	}
+	ra, err := alice.mgr.load(bob.id)
+	require.NoError(t, err)
+	rb, err := bob.mgr.load(alice.id)
+	require.NoError(t, err)
+	require.Equal(t, ra.Sessions[0].Ephemeral, rb.Sessions[0].Ephemeral)
+}
+
+func TestSealedState(t *testing.T) {
+	alice, bob := newParty(t), newParty(t)
+	receive(t, bob, alice, send(t, alice, bob, "hi bob"), "hi bob")
+
+	// the keys are not readable from the store
+	bs, err := alice.s.SessionByPeer(bob.id.String())
+	require.NoError(t, err)
+	require.Equal(t, byte(stateVersion), bs.State[0])
+	require.False(t, json.Valid(bs.State))
+	rec, err := alice.mgr.load(bob.id)
+	require.NoError(t, err)
+	require.NotContains(t, string(bs.State), base64.StdEncoding.EncodeToString(rec.Sessions[0].RK))
+
+	// nor with another identity
+	sk, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	other, err := NewManager(alice.s, sk)
+	require.NoError(t, err)
+	_, err = other.Encrypt(bob.id, []byte("hi"))
+	require.ErrorIs(t, err, ErrSealedState)
+
+	// sessions stored before they were sealed are still read
+	plain, err := json.Marshal(rec)
+	require.NoError(t, err)
+	require.NoError(t, alice.s.PutSession(store.BHSession{PeerID: bob.id.String(), State: plain}))
+	alice.restart(t)
+	receive(t, bob, alice, send(t, alice, bob, "still here"), "still here")
+}
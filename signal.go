@@ -0,0 +1,233 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/event"
+)
+
+// how long typing and presence hold unless they are sent again, presence
+// is sent again by the messenger every half of PresenceTTL
+var (
+	TypingTTL   = 6 * time.Second
+	PresenceTTL = 2 * time.Minute
+)
+
+// longest state a peer can ask for
+const maxSignalTTL = 10 * time.Minute
+
+// Typing tells the online members of a chat that I started or stopped
+// typing. Typing stops by itself after TypingTTL, it is sent again while
+// the user keeps typing.
+func (c *Chat) Typing(chatID entity.ID, typing bool) error {
+	chat, err := c.chRepo.GetByID(chatID)
+	if err != nil {
+		return err
+	}
+	s := entity.Signal{ChatID: chatID, Kind: entity.TypingStopped}
+	if typing {
+		s.Kind = entity.TypingStarted
+		s.TTL = int64(TypingTTL / time.Second)
+	}
+	return c.signal(chat, s)
+}
+
+// SetAway tells the members of my chats whether I am away. Unless I am
+// away the messenger keeps telling them I am online.
+func (c *Chat) SetAway(away bool) error {
+	c.signals.setAway(away)
+	if !away {
+		return c.announce()
+	}
+	chats, err := c.ChatInfos(entity.Page{})
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats.Items {
+		c.signal(chat, entity.Signal{ChatID: chat.ID, Kind: entity.Away})
+	}
+	return nil
+}
+
+// Presence returns whether a contact is online and when I last heard of it.
+func (c *Chat) Presence(id entity.ID) entity.Presence {
+	return c.signals.get(id)
+}
+
+// announce tells the members of my chats that I am online.
+func (c *Chat) announce() error {
+	if c.signals.isAway() {
+		return nil
+	}
+	chats, err := c.ChatInfos(entity.Page{})
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats.Items {
+		s := entity.Signal{ChatID: chat.ID, Kind: entity.Online, TTL: int64(PresenceTTL / time.Second)}
+		// the members that are offline will hear of it next time
+		c.signal(chat, s)
+	}
+	return nil
+}
+
+// signal sends s to the online members of chat, it is dropped for the
+// others.
+func (c *Chat) signal(chat entity.ChatInfo, s entity.Signal) error {
+	if chat.Type == entity.Group {
+		return c.gps.Signal(chat.ID, s)
+	}
+	me, err := c.Identity.Get()
+	if err != nil {
+		return err
+	}
+	for _, to := range chat.Members {
+		if to.ID == me.ID {
+			continue
+		}
+		err = c.pms.Signal(to, s)
+		if err != nil && err != ErrOffline {
+			return err
+		}
+	}
+	return nil
+}
+
+// signaled takes a signal of a member of its chat.
+func (c *Chat) signaled(s entity.Signal) error {
+	chat, err := c.chRepo.GetByID(s.ChatID)
+	if err != nil {
+		return err
+	}
+	if !chat.IsMember(s.From) {
+		return ErrNotMember
+	}
+	c.signals.received(s)
+	return nil
+}
+
+type presence struct {
+	entity.Presence
+	expiry *time.Timer
+}
+
+// signals keeps the typing and presence of peers and ends them when they
+// are not sent again in time.
+type signals struct {
+	bus Bus
+	mux sync.Mutex
+	// typing expiry by chat and peer
+	typing   map[string]*time.Timer
+	presence map[entity.ID]*presence
+	away     bool
+}
+
+func newSignals(bus Bus) *signals {
+	return &signals{
+		bus:      bus,
+		typing:   make(map[string]*time.Timer),
+		presence: make(map[entity.ID]*presence),
+	}
+}
+
+func (t *signals) received(s entity.Signal) {
+	t.mux.Lock()
+	p, ok := t.presence[s.From]
+	if !ok {
+		p = &presence{Presence: entity.Presence{ID: s.From}}
+		t.presence[s.From] = p
+	}
+	p.LastSeen = s.At
+	key := s.ChatID.String() + "/" + s.From.String()
+	switch s.Kind {
+	case entity.TypingStarted:
+		if tm, ok := t.typing[key]; ok {
+			tm.Stop()
+		}
+		var tm *time.Timer
+		tm = time.AfterFunc(signalTTL(s.TTL, TypingTTL), func() { t.typingExpired(key, tm, s) })
+		t.typing[key] = tm
+	case entity.TypingStopped:
+		if tm, ok := t.typing[key]; ok {
+			tm.Stop()
+			delete(t.typing, key)
+		}
+	case entity.Online:
+		p.Online = true
+		if p.expiry != nil {
+			p.expiry.Stop()
+		}
+		var tm *time.Timer
+		tm = time.AfterFunc(signalTTL(s.TTL, PresenceTTL), func() { t.presenceExpired(p, tm, s) })
+		p.expiry = tm
+	case entity.Away:
+		p.Online = false
+		if p.expiry != nil {
+			p.expiry.Stop()
+			p.expiry = nil
+		}
+	}
+	t.mux.Unlock()
+	event.EmitSignal(t.bus, s)
+}
+
+// typingExpired stops typing of a peer that did not send it again.
+func (t *signals) typingExpired(key string, tm *time.Timer, s entity.Signal) {
+	t.mux.Lock()
+	if t.typing[key] != tm {
+		t.mux.Unlock()
+		return
+	}
+	delete(t.typing, key)
+	t.mux.Unlock()
+	event.EmitSignal(t.bus, entity.Signal{ChatID: s.ChatID, Kind: entity.TypingStopped, From: s.From, At: time.Now().Unix()})
+}
+
+// presenceExpired takes a peer that did not tell it is online for away.
+func (t *signals) presenceExpired(p *presence, tm *time.Timer, s entity.Signal) {
+	t.mux.Lock()
+	if p.expiry != tm {
+		t.mux.Unlock()
+		return
+	}
+	p.Online = false
+	p.expiry = nil
+	t.mux.Unlock()
+	event.EmitSignal(t.bus, entity.Signal{ChatID: s.ChatID, Kind: entity.Away, From: s.From, At: time.Now().Unix()})
+}
+
+func (t *signals) get(id entity.ID) entity.Presence {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	p, ok := t.presence[id]
+	if !ok {
+		return entity.Presence{ID: id}
+	}
+	return p.Presence
+}
+
+func (t *signals) setAway(away bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.away = away
+}
+
+func (t *signals) isAway() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.away
+}
+
+// signalTTL is the lifetime asked for by a peer, bounded by maxSignalTTL.
+func signalTTL(seconds int64, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	ttl := time.Duration(seconds) * time.Second
+	if ttl > maxSignalTTL {
+		return maxSignalTTL
+	}
+	return ttl
+}
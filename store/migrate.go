@@ -0,0 +1,116 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+var ErrSchemaTooNew = errors.New("store was written by a newer version")
+
+// SchemaVersion is the version of the data written by this store, stores
+// without a BHMeta are at version zero.
+const SchemaVersion = 2
+
+const metaKey = "meta"
+
+// BHMeta describes the stored data.
+type BHMeta struct {
+	SchemaVersion int
+}
+
+// migration upgrades the data of the previous version to version. The
+// version is recorded once a migration is done, a migration cut short is
+// run again from the start so it must be safe to repeat.
+type migration struct {
+	version int
+	name    string
+	up      func(s *Store) error
+}
+
+var migrations = []migration{
+	{1, "index the messages for search", indexMessages},
+	{2, "summarize the chats", summarizeChats},
+}
+
+// Version returns the schema version of the stored data.
+func (s *Store) Version() (int, error) {
+	var meta BHMeta
+	err := s.bh.Get(metaKey, &meta)
+	if err == badgerhold.ErrNotFound {
+		return 0, nil
+	}
+	return meta.SchemaVersion, err
+}
+
+// migrate upgrades the stored data to SchemaVersion one version at a time.
+func (s *Store) migrate() error {
+	version, err := s.Version()
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return ErrSchemaTooNew
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		log.Infof("migrating store to version %d: %s", m.version, m.name)
+		err = m.up(s)
+		if err != nil {
+			return fmt.Errorf("migrate store to version %d: %w", m.version, err)
+		}
+		err = s.bh.Upsert(metaKey, BHMeta{SchemaVersion: m.version})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexMessages adds the messages stored before search to the index.
+func indexMessages(s *Store) error {
+	var msgs []BHTextMessage
+	err := s.bh.Find(&msgs, nil)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		err = s.unindexMessage(msg.ID)
+		if err != nil {
+			return err
+		}
+		err = s.indexMessage(msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summarizeChats computes the summaries of the chats stored before them.
+// When they were created is not known, so the chats without messages are
+// kept at the bottom instead of being the latest active.
+func summarizeChats(s *Store) error {
+	var chats []BHChat
+	err := s.bh.Find(&chats, nil)
+	if err != nil {
+		return err
+	}
+	for _, ch := range chats {
+		err = s.bh.Badger().Update(func(tx *badger.Txn) error {
+			err := s.resummarize(tx, &ch, 0)
+			if err != nil {
+				return err
+			}
+			return s.bh.TxUpdate(tx, ch.ID, ch)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/store"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// openFixture restores the backup testdata/name in a new directory and
+// returns the directory.
+func openFixture(t *testing.T, name string) string {
+	dir := t.TempDir()
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	require.NoError(t, db.Load(f, 16))
+	require.NoError(t, db.Close())
+	return dir
+}
+
+// search returns the ids of the messages matching terms.
+func search(t *testing.T, s *store.Store, terms ...string) []string {
+	hits, _, err := s.SearchMessages(store.SearchQuery{Terms: terms}, store.Seek{})
+	require.NoError(t, err)
+	ids := make([]string, 0)
+	for _, h := range hits {
+		ids = append(ids, h.Msg.ID)
+	}
+	return ids
+}
+
+// requireMigrated checks the fixture chats and messages are indexed and
+// summarized, the fixtures hold the same three chats and five messages.
+func requireMigrated(t *testing.T, s *store.Store) []store.BHChat {
+	v, err := s.Version()
+	require.NoError(t, err)
+	require.Equal(t, store.SchemaVersion, v)
+
+	// the old messages are found
+	require.Equal(t, []string{"m1"}, search(t, s, "lunch"))
+	require.Equal(t, []string{"m4"}, search(t, s, "beach"))
+
+	// the chats are summarized and listed by activity
+	chats, _, err := s.ChatList(store.Seek{})
+	require.NoError(t, err)
+	require.Len(t, chats, 3)
+	require.Equal(t, "friends", chats[0].ID)
+	require.Equal(t, "m5", chats[0].Summary.LastMsgID)
+	require.Equal(t, uint64(2), chats[0].Summary.Unread)
+	require.Equal(t, "alicebob", chats[1].ID)
+	require.Equal(t, "m3", chats[1].Summary.LastMsgID)
+	require.Equal(t, "See you at noon", chats[1].Summary.LastText)
+	require.Equal(t, "bob", chats[1].Summary.LastAuthor.ID)
+	require.Equal(t, int64(1020), chats[1].Summary.LastAt)
+	require.Equal(t, uint64(1), chats[1].Summary.Unread)
+	// an empty chat is not made the latest active by the migration
+	require.Equal(t, "alicecarol", chats[2].ID)
+	require.Empty(t, chats[2].Summary.LastMsgID)
+	require.Zero(t, chats[2].Summary.LastAt)
+	return chats
+}
+
+// v0.bak was written by the first release, before key encryption,
+// attachments, expiry, search and chat summaries: an identity with a plain
+// key, three chats, alicecarol without messages, and five messages.
+func TestMigrateV0(t *testing.T) {
+	dir := openFixture(t, "v0.bak")
+	s, err := store.NewStore(dir)
+	require.NoError(t, err)
+	chats := requireMigrated(t, s)
+
+	// the identity keeps its plain key
+	bh, err := s.GetIdentity()
+	require.NoError(t, err)
+	iden := entity.Identity{ID: entity.ID(bh.ID), Name: bh.Name, PrivKey: bh.Key}
+	require.False(t, iden.IsEncrypted())
+	sk, err := iden.DecodePrivateKey("")
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+	require.Equal(t, bh.ID, id.String())
+
+	// a migrated store opens as is
+	s.Close()
+	s, err = store.NewStore(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, []string{"m2"}, search(t, s, "usual"))
+	again, _, err := s.ChatList(store.Seek{})
+	require.NoError(t, err)
+	require.Equal(t, chats, again)
+}
+
+// v0-expiry.bak was written after disappearing messages, before search and
+// chat summaries: the v0 chats with an expiry on friends, an attachment on
+// m4 and m5 deleted.
+func TestMigrateV0Expiry(t *testing.T) {
+	dir := openFixture(t, "v0-expiry.bak")
+	s, err := store.NewStore(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	requireMigrated(t, s)
+
+	// the data is kept
+	ch, err := s.ChatByID("friends")
+	require.NoError(t, err)
+	require.Equal(t, int64(3600), ch.Expiry)
+	require.Len(t, ch.Admins, 1)
+	msg, err := s.MsgByID("m4")
+	require.NoError(t, err)
+	require.Equal(t, "beach.jpg", msg.Attachments[0].Name)
+	msg, err = s.MsgByID("m5")
+	require.NoError(t, err)
+	require.True(t, msg.Deleted)
+}
+
+func TestMigrateNewer(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.NewStore(dir)
+	require.NoError(t, err)
+	v, err := s.Version()
+	require.NoError(t, err)
+	require.Equal(t, store.SchemaVersion, v)
+	s.Close()
+
+	opt := badgerhold.DefaultOptions
+	opt.Dir = dir
+	opt.ValueDir = dir
+	bh, err := badgerhold.Open(opt)
+	require.NoError(t, err)
+	require.NoError(t, bh.Upsert("meta", store.BHMeta{SchemaVersion: store.SchemaVersion + 1}))
+	require.NoError(t, bh.Close())
+
+	_, err = store.NewStore(dir)
+	require.ErrorIs(t, err, store.ErrSchemaTooNew)
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Seek reads a page of a list ordered by a key then by id. Without From the
+// page starts at the beginning of the list, or ends at its end when Before.
+type Seek struct {
+	// position the page starts after, or ends before
+	Key    int64
+	ID     string
+	From   bool
+	Before bool
+	// all items when zero
+	Limit int
+}
+
+// ordering of a list, keyField is empty for lists ordered by id only
+type ordering[T any] struct {
+	keyField string
+	desc     bool
+	key      func(*T) (int64, string)
+}
+
+var (
+	messageOrder = ordering[BHTextMessage]{"CreatedAt", true, func(m *BHTextMessage) (int64, string) { return m.CreatedAt, m.ID }}
+	threadOrder  = ordering[BHTextMessage]{"CreatedAt", false, func(m *BHTextMessage) (int64, string) { return m.CreatedAt, m.ID }}
+	requestOrder = ordering[BHChatRequest]{"CreatedAt", true, func(r *BHChatRequest) (int64, string) { return r.CreatedAt, r.ID }}
+	chatOrder    = ordering[BHChat]{"Summary.LastAt", true, func(c *BHChat) (int64, string) { return c.Summary.LastAt, c.ID }}
+	contactOrder = ordering[BHContact]{"", false, func(c *BHContact) (int64, string) { return 0, c.ID }}
+)
+
+// find reads the page p of the items matching q, or of all items when q is
+// nil. It returns the items in list order and whether the list goes on past
+// the page, after it or before it when p.Before.
+func find[T any](s *Store, q *badgerhold.Query, o ordering[T], p Seek) ([]T, bool, error) {
+	// read away from the position, in list order unless reading before it
+	asc := o.desc == p.Before
+	if p.From {
+		past := func(ra *badgerhold.RecordAccess) (bool, error) {
+			key, id := o.key(ra.Record().(*T))
+			if key == p.Key {
+				return id != p.ID && (id > p.ID) == asc, nil
+			}
+			return (key > p.Key) == asc, nil
+		}
+		if q == nil {
+			q = badgerhold.Where("ID").MatchFunc(past)
+		} else {
+			q = q.And("ID").MatchFunc(past)
+		}
+	} else if q == nil {
+		q = &badgerhold.Query{}
+	}
+	if o.keyField != "" {
+		q = q.SortBy(o.keyField, "ID")
+	} else {
+		q = q.SortBy("ID")
+	}
+	if !asc {
+		q = q.Reverse()
+	}
+	if p.Limit > 0 {
+		q = q.Limit(p.Limit + 1)
+	}
+	var res []T
+	err := s.bh.Find(&res, q)
+	if err != nil {
+		return nil, false, err
+	}
+	more := p.Limit > 0 && len(res) > p.Limit
+	if more {
+		res = res[:p.Limit]
+	}
+	if p.Before {
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+	return res, more, nil
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// longest indexed term, in runes
+const maxTermLength = 64
+
+// BHSearchTerm tells a message contains a term, Key is the term and the
+// message id. The fields of the message are copied to filter without
+// loading it.
+type BHSearchTerm struct {
+	Key       string `badgerhold:"unique"`
+	Term      string `badgerhold:"index"`
+	MsgID     string `badgerhold:"index"`
+	ChatID    string
+	Author    string
+	CreatedAt int64
+	// occurrences of the term in the message
+	Count int
+}
+
+// Token is a word of a text, Start and End are rune offsets.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text in lower case words of letters and digits.
+func Tokenize(text string) []Token {
+	var res []Token
+	start := -1
+	var word []rune
+	i := 0
+	flush := func() {
+		if start >= 0 {
+			if len(word) > maxTermLength {
+				word = word[:maxTermLength]
+			}
+			res = append(res, Token{Term: string(word), Start: start, End: i})
+		}
+		start = -1
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if start < 0 {
+				start = i
+			}
+			word = append(word, unicode.ToLower(r))
+		} else {
+			flush()
+		}
+		i++
+	}
+	flush()
+	return res
+}
+
+// SearchQuery finds messages with all Terms, the other fields filter
+// them when set.
+type SearchQuery struct {
+	Terms   []string
+	ChatID  string
+	Authors []string
+	// unix times, inclusive
+	Since int64
+	Until int64
+}
+
+type SearchHit struct {
+	Msg   BHTextMessage
+	Score float64
+}
+
+// indexMessage adds the words of the text and attachment names of tm to
+// the index, deleted messages are not indexed.
+func (s *Store) indexMessage(tm BHTextMessage) error {
+	if tm.Deleted {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, t := range Tokenize(tm.Text) {
+		counts[t.Term]++
+	}
+	for _, a := range tm.Attachments {
+		for _, t := range Tokenize(a.Name) {
+			counts[t.Term]++
+		}
+	}
+	for term, n := range counts {
+		st := BHSearchTerm{
+			Key:       term + "/" + tm.ID,
+			Term:      term,
+			MsgID:     tm.ID,
+			ChatID:    tm.ChatID,
+			Author:    tm.Author.ID,
+			CreatedAt: tm.CreatedAt,
+			Count:     n,
+		}
+		err := s.bh.Upsert(st.Key, st)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) unindexMessage(msgID string) error {
+	return s.bh.DeleteMatching(BHSearchTerm{}, badgerhold.Where("MsgID").Eq(msgID).Index("MsgID"))
+}
+
+// SearchMessages returns the page p of the messages with all terms of q,
+// the best matches first, and whether the list goes on past the page. Rare
+// terms and terms repeated in a message weigh more, ties are broken by the
+// newest message. The key of p is the bits of the score of its message, a
+// page after a message that no longer matches goes on from its score.
+func (s *Store) SearchMessages(q SearchQuery, p Seek) ([]SearchHit, bool, error) {
+	if len(q.Terms) == 0 {
+		return nil, false, nil
+	}
+	total, err := s.bh.Count(&BHTextMessage{}, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	authors := make(map[string]bool)
+	for _, a := range q.Authors {
+		authors[a] = true
+	}
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	created := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, term := range q.Terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		var postings []BHSearchTerm
+		err := s.bh.Find(&postings, badgerhold.Where("Term").Eq(term).Index("Term"))
+		if err != nil {
+			return nil, false, err
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(total)-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			if q.ChatID != "" && p.ChatID != q.ChatID {
+				continue
+			}
+			if len(authors) > 0 && !authors[p.Author] {
+				continue
+			}
+			if (q.Since > 0 && p.CreatedAt < q.Since) || (q.Until > 0 && p.CreatedAt > q.Until) {
+				continue
+			}
+			tf := float64(p.Count)
+			scores[p.MsgID] += idf * tf / (tf + 1)
+			matched[p.MsgID]++
+			created[p.MsgID] = p.CreatedAt
+		}
+	}
+
+	ids := make([]string, 0)
+	for id, n := range matched {
+		if n == len(seen) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if created[a] != created[b] {
+			return created[a] > created[b]
+		}
+		return strings.Compare(a, b) < 0
+	})
+	if p.From {
+		// the message of the cursor, or the first one scored below it
+		at, found := len(ids), false
+		for i, id := range ids {
+			if id == p.ID {
+				at, found = i, true
+				break
+			}
+		}
+		for i := 0; !found && i < len(ids); i++ {
+			if int64(math.Float64bits(scores[ids[i]])) < p.Key {
+				at = i
+				break
+			}
+		}
+		if found && !p.Before {
+			at++
+		}
+		if p.Before {
+			ids = ids[:at]
+		} else {
+			ids = ids[at:]
+		}
+	}
+	more := p.Limit > 0 && len(ids) > p.Limit
+	if more && p.Before {
+		ids = ids[len(ids)-p.Limit:]
+	} else if more {
+		ids = ids[:p.Limit]
+	}
+	res := make([]SearchHit, 0, len(ids))
+	for _, id := range ids {
+		msg, err := s.MsgByID(id)
+		if err == badgerhold.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		res = append(res, SearchHit{Msg: msg, Score: scores[id]})
+	}
+	return res, more, nil
+}
@@ -1,6 +1,10 @@
 package store
 
 import (
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
 	"github.com/hood-chat/core/entity"
 	"github.com/timshannon/badgerhold/v4"
 
@@ -9,6 +13,8 @@ import (
 
 var log = logging.Logger("msgr-core-store")
 
+var ErrNotFound = badgerhold.ErrNotFound
+
 
 type BHIdentity struct {
 	ID   string `badgerhold:"unique"`
@@ -27,22 +33,133 @@ type BHChat struct {
 	Members []BHContact
 	Type    entity.ChatType
 	Admins  []BHContact
+	Expiry  int64
+	Summary BHChatSummary
 }
 
 type BHTextMessage struct {
 	// BID       int64 `badgerhold:"key"`
+	ID          string `badgerhold:"unique"`
+	ChatID      string `badgerhold:"index"`
+	CreatedAt   int64
+	Text        string
+	Status      entity.Status
+	Author      BHContact
+	Sig         []byte
+	Attachments []BHAttachment
+	EditedAt    int64
+	Deleted     bool
+	ReplyTo     *BHReply
+	ThreadID    string `badgerhold:"index"`
+	// latest reaction of each author and emoji
+	Reactions []BHReaction
+	// zero when the message does not expire
+	ExpiresAt int64 `badgerhold:"index"`
+}
+
+type BHReaction struct {
+	Emoji   string
+	Author  string
+	At      int64
+	Removed bool
+}
+
+type BHReply struct {
+	ID      string
+	Author  BHContact
+	Snippet string
+}
+
+// BHMessageVersion is an earlier text of an edited message.
+type BHMessageVersion struct {
+	// message id and creation time of the text
+	Key       string `badgerhold:"unique"`
+	MsgID     string `badgerhold:"index"`
+	Text      string
+	CreatedAt int64
+}
+
+type BHAttachment struct {
+	Hash string
+	Name string
+	Mime string
+	Size int64
+}
+
+// BHBlobRef records that a chat message references a blob, it tells who
+// may fetch the blob.
+type BHBlobRef struct {
+	// blob hash and message id
+	Key    string `badgerhold:"unique"`
+	Hash   string `badgerhold:"index"`
+	ChatID string
+}
+
+// BHSession holds the session state with a peer, sealed with a key derived
+// from the identity key.
+type BHSession struct {
+	PeerID string `badgerhold:"unique"`
+	State  []byte
+}
+
+// BHEnvelop is an outgoing envelop waiting for delivery, Payload holds
+// the proto encoded message.
+type BHEnvelop struct {
+	// peer id and envelop id
+	Key      string `badgerhold:"unique"`
+	ID       string
+	To       BHContact
+	Protocol string
+	Payload  []byte
+	// the proto encoded secure message once the message is encrypted
+	Sealed    []byte
+	CreatedAt int64
+}
+
+// BHChatRequest is a received group invite, ID is the chat id.
+type BHChatRequest struct {
 	ID        string `badgerhold:"unique"`
-	ChatID    string `badgerhold:"index"`
+	Chat      BHChat
+	From      BHContact
 	CreatedAt int64
-	Text      string
-	Status    entity.Status
-	Author    BHContact
+}
+
+// BHInvitation is a sent group invite, Key is the chat id and contact id.
+type BHInvitation struct {
+	Key    string `badgerhold:"unique"`
+	ChatID string `badgerhold:"index"`
+	To     BHContact
+	Status entity.InviteStatus
+}
+
+// BHMailboxEnvelope is kept by a mailbox for one of its owners, Key is the
+// owner, the sender and the envelope id so that a sender only replaces its
+// own envelopes.
+type BHMailboxEnvelope struct {
+	Key       string `badgerhold:"unique"`
+	To        string `badgerhold:"index"`
+	ID        string
+	From      string
+	Protocol  string
+	Payload   []byte
+	CreatedAt int64
+}
+
+// BHMailboxOwner is a peer that uses this node as its mailbox.
+type BHMailboxOwner struct {
+	ID           string `badgerhold:"unique"`
+	RegisteredAt int64
+	// latest MailboxEndorsement of the owner, shown to senders
+	Endorsement []byte
 }
 
 type Store struct {
 	bh badgerhold.Store
+	// serializes read-modify-write of messages, shared by the copies
+	mux *sync.Mutex
 }
 
+// NewStore opens the store at path and upgrades its data to SchemaVersion.
 func NewStore(path string) (*Store, error) {
 	opt := badgerhold.DefaultOptions
 	opt.Dir = path
@@ -53,9 +170,16 @@ func NewStore(path string) (*Store, error) {
 		return nil, err
 	}
 
-	return &Store{
-		bh: *store,
-	}, nil
+	s := &Store{
+		bh:  *store,
+		mux: &sync.Mutex{},
+	}
+	err = s.migrate()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
 
 }
 
@@ -77,28 +201,140 @@ func (s *Store) PutContact(contact BHContact) error {
 }
 
 func (s *Store) InsertTextMessage(tm BHTextMessage) error {
-	err := s.bh.Insert(tm.ID, tm)
-	return err
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	err := s.bh.Badger().Update(func(tx *badger.Txn) error {
+		err := s.bh.TxInsert(tx, tm.ID, tm)
+		if err != nil {
+			return err
+		}
+		return s.summarize(tx, nil, &tm)
+	})
+	if err != nil {
+		return err
+	}
+	for _, a := range tm.Attachments {
+		ref := BHBlobRef{Key: a.Hash + "/" + tm.ID, Hash: a.Hash, ChatID: tm.ChatID}
+		err = s.bh.Upsert(ref.Key, ref)
+		if err != nil {
+			return err
+		}
+	}
+	return s.indexMessage(tm)
 }
 
-func (s *Store) InsertChat(ch BHChat) error {
-	err := s.bh.Insert(ch.ID, ch)
-	return err
+// ThreadMessages returns a page of the messages of a thread without its
+// first message, the oldest first.
+func (s *Store) ThreadMessages(threadID string, p Seek) ([]BHTextMessage, bool, error) {
+	q := badgerhold.Where("ThreadID").Eq(threadID).Index("ThreadID")
+	return find(s, q, threadOrder, p)
 }
 
-func (s *Store) ChatList(skip int, limit int) ([]BHChat, error) {
-	var res []BHChat
-	q := &badgerhold.Query{}
-	if skip > 0 && limit > 0 {
-		q.Limit(limit)
-		q.Skip(skip)
+// DeleteMessage removes a message and its blob references.
+func (s *Store) DeleteMessage(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.deleteMessage(id)
+}
+
+func (s *Store) deleteMessage(id string) error {
+	msg, err := s.MsgByID(id)
+	if err != nil {
+		return err
+	}
+	err = s.deleteBlobRefs(msg)
+	if err != nil {
+		return err
+	}
+	err = s.unindexMessage(id)
+	if err != nil {
+		return err
+	}
+	return s.bh.Badger().Update(func(tx *badger.Txn) error {
+		err := s.bh.TxDelete(tx, id, BHTextMessage{})
+		if err != nil {
+			return err
+		}
+		return s.summarize(tx, &msg, nil)
+	})
+}
+
+func (s *Store) deleteBlobRefs(msg BHTextMessage) error {
+	for _, a := range msg.Attachments {
+		err := s.bh.Delete(a.Hash+"/"+msg.ID, BHBlobRef{})
+		if err != nil && err != badgerhold.ErrNotFound {
+			return err
+		}
 	}
-	err := s.bh.Find(&res, q)
+	return nil
+}
+
+func (s *Store) PutMessageVersion(v BHMessageVersion) error {
+	return s.bh.Upsert(v.Key, v)
+}
+
+func (s *Store) MessageVersions(msgID string) ([]BHMessageVersion, error) {
+	var res []BHMessageVersion
+	err := s.bh.Find(&res, badgerhold.Where("MsgID").Eq(msgID).Index("MsgID").SortBy("CreatedAt"))
 	return res, err
 }
 
-func (s *Store) ChatMessages(id string, skip int, limit int, statuses ...entity.Status) ([]BHTextMessage, error) {
-	var res []BHTextMessage
+func (s *Store) DeleteMessageVersions(msgID string) error {
+	return s.bh.DeleteMatching(BHMessageVersion{}, badgerhold.Where("MsgID").Eq(msgID).Index("MsgID"))
+}
+
+// BlobChats returns the chats with messages that reference the blob.
+func (s *Store) BlobChats(hash string) ([]string, error) {
+	var refs []BHBlobRef
+	err := s.bh.Find(&refs, badgerhold.Where("Hash").Eq(hash).Index("Hash"))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(refs))
+	for _, r := range refs {
+		res = append(res, r.ChatID)
+	}
+	return res, nil
+}
+
+// InsertChat adds a chat with the summary of the messages already stored
+// for it, a new chat without messages is the latest active.
+func (s *Store) InsertChat(ch BHChat) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.bh.Badger().Update(func(tx *badger.Txn) error {
+		err := s.resummarize(tx, &ch, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+		return s.bh.TxInsert(tx, ch.ID, ch)
+	})
+}
+
+// UpdateChat stores ch, its summary is kept as stored, it only changes
+// with its messages.
+func (s *Store) UpdateChat(ch BHChat) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.bh.Badger().Update(func(tx *badger.Txn) error {
+		var old BHChat
+		err := s.bh.TxGet(tx, ch.ID, &old)
+		if err != nil {
+			return err
+		}
+		ch.Summary = old.Summary
+		return s.bh.TxUpdate(tx, ch.ID, ch)
+	})
+}
+
+// ChatList returns a page of the chats, the latest active first.
+func (s *Store) ChatList(p Seek) ([]BHChat, bool, error) {
+	return find(s, nil, chatOrder, p)
+}
+
+// ChatMessages returns a page of the messages of a chat, the newest first,
+// only those with one of statuses when given.
+func (s *Store) ChatMessages(id string, p Seek, statuses ...entity.Status) ([]BHTextMessage, bool, error) {
 	q := badgerhold.Where("ChatID").Eq(id)
 	if len(statuses) > 0 {
 		s := make([]interface{},0)
@@ -108,13 +344,7 @@ func (s *Store) ChatMessages(id string, skip int, limit int, statuses ...entity.
 		
 		q.And("Status").In(s...)
 	}
-	if skip > 0 && limit > 0 {
-		q.Limit(limit)
-		q.Skip(skip)
-	}
-	q.SortBy("CreatedAt").Reverse()
-	err := s.bh.Find(&res, q)
-	return res, err
+	return find(s, q, messageOrder, p)
 }
 
 func (s *Store) ChatUnreadCount(id string) (uint64, error) {
@@ -129,20 +359,75 @@ func (s *Store) MsgByID(id string) (BHTextMessage, error) {
 	return res, err
 }
 
+// UpdateMessage stores msg, its reactions are kept as stored, they only
+// change with ReactToMessage.
 func (s *Store) UpdateMessage(msg BHTextMessage) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 	// tx := s.bh.Badger().NewTransaction(true)
-	return s.bh.Update(msg.ID, msg)
+	old, err := s.MsgByID(msg.ID)
+	if err != nil {
+		return err
+	}
+	if msg.Deleted {
+		// attachments of a deleted message are no longer served
+		err = s.deleteBlobRefs(old)
+		if err != nil {
+			return err
+		}
+	}
+	msg.Reactions = old.Reactions
+	err = s.bh.Badger().Update(func(tx *badger.Txn) error {
+		err := s.bh.TxUpdate(tx, msg.ID, msg)
+		if err != nil {
+			return err
+		}
+		return s.summarize(tx, &old, &msg)
+	})
+	if err != nil {
+		return err
+	}
+	if msg.Text == old.Text && msg.Deleted == old.Deleted {
+		return nil
+	}
+	err = s.unindexMessage(msg.ID)
+	if err != nil {
+		return err
+	}
+	return s.indexMessage(msg)
 }
 
-func (s *Store) AllContacts(skip int, limit int) ([]BHContact, error) {
-	var res []BHContact
-	q := &badgerhold.Query{}
-	if skip > 0 && limit > 0 {
-		q.Limit(limit)
-		q.Skip(skip)
+// ReactToMessage records r unless the author changed the same emoji later,
+// it returns whether the message changed.
+func (s *Store) ReactToMessage(id string, r BHReaction) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	msg, err := s.MsgByID(id)
+	if err != nil {
+		return false, err
 	}
-	err := s.bh.Find(&res, q)
-	return res, err
+	for i, old := range msg.Reactions {
+		if old.Emoji != r.Emoji || old.Author != r.Author {
+			continue
+		}
+		if old.At > r.At || old.Removed == r.Removed {
+			return false, nil
+		}
+		msg.Reactions[i] = r
+		return true, s.bh.Update(id, msg)
+	}
+	if r.Removed {
+		// keep it, a late add must not bring the reaction back
+		msg.Reactions = append(msg.Reactions, r)
+		return false, s.bh.Update(id, msg)
+	}
+	msg.Reactions = append(msg.Reactions, r)
+	return true, s.bh.Update(id, msg)
+}
+
+// AllContacts returns a page of the contacts ordered by id.
+func (s *Store) AllContacts(p Seek) ([]BHContact, bool, error) {
+	return find(s, nil, contactOrder, p)
 }
 
 func (s *Store) ContactByIDs(ids []string) ([]BHContact, error) {
@@ -165,7 +450,7 @@ func (s *Store) ChatByID(id string) (BHChat, error) {
 }
 
 func (s *Store) SetIdentity(id BHIdentity) error {
-	err := s.bh.Insert(id.ID, id)
+	err := s.bh.Upsert(id.ID, id)
 	return err
 }
 
@@ -179,6 +464,112 @@ func (s *Store) GetIdentity() (BHIdentity, error) {
 	return res, err
 }
 
+func (s *Store) SessionByPeer(id string) (BHSession, error) {
+	var res BHSession
+	err := s.bh.Get(id, &res)
+	return res, err
+}
+
+func (s *Store) PutSession(ses BHSession) error {
+	return s.bh.Upsert(ses.PeerID, ses)
+}
+
+func (s *Store) PutEnvelop(e BHEnvelop) error {
+	return s.bh.Upsert(e.Key, e)
+}
+
+func (s *Store) DeleteEnvelop(key string) error {
+	err := s.bh.Delete(key, BHEnvelop{})
+	if err == badgerhold.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) Envelops() ([]BHEnvelop, error) {
+	var res []BHEnvelop
+	err := s.bh.Find(&res, &badgerhold.Query{})
+	return res, err
+}
+
+func (s *Store) PutChatRequest(r BHChatRequest) error {
+	return s.bh.Upsert(r.ID, r)
+}
+
+func (s *Store) ChatRequestByID(id string) (BHChatRequest, error) {
+	var res BHChatRequest
+	err := s.bh.Get(id, &res)
+	return res, err
+}
+
+// ChatRequests returns a page of the chat requests, the newest first.
+func (s *Store) ChatRequests(p Seek) ([]BHChatRequest, bool, error) {
+	return find(s, nil, requestOrder, p)
+}
+
+func (s *Store) DeleteChatRequest(id string) error {
+	err := s.bh.Delete(id, BHChatRequest{})
+	if err == badgerhold.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) PutInvitation(i BHInvitation) error {
+	return s.bh.Upsert(i.Key, i)
+}
+
+func (s *Store) InvitationByKey(key string) (BHInvitation, error) {
+	var res BHInvitation
+	err := s.bh.Get(key, &res)
+	return res, err
+}
+
+func (s *Store) ChatInvitations(chatID string) ([]BHInvitation, error) {
+	var res []BHInvitation
+	err := s.bh.Find(&res, badgerhold.Where("ChatID").Eq(chatID).Index("ChatID"))
+	return res, err
+}
+
+func (s *Store) PutMailboxEnvelope(e BHMailboxEnvelope) error {
+	return s.bh.Upsert(e.Key, e)
+}
+
+func (s *Store) MailboxEnvelopes(to string) ([]BHMailboxEnvelope, error) {
+	var res []BHMailboxEnvelope
+	q := badgerhold.Where("To").Eq(to).Index("To")
+	err := s.bh.Find(&res, q.SortBy("CreatedAt"))
+	return res, err
+}
+
+func (s *Store) MailboxEnvelopeCount(to string) (uint64, error) {
+	return s.bh.Count(&BHMailboxEnvelope{}, badgerhold.Where("To").Eq(to).Index("To"))
+}
+
+func (s *Store) DeleteMailboxEnvelope(key string) error {
+	err := s.bh.Delete(key, BHMailboxEnvelope{})
+	if err == badgerhold.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) PutMailboxOwner(o BHMailboxOwner) error {
+	return s.bh.Upsert(o.ID, o)
+}
+
+func (s *Store) MailboxOwnerByID(id string) (BHMailboxOwner, error) {
+	var res BHMailboxOwner
+	err := s.bh.Get(id, &res)
+	return res, err
+}
+
+func (s *Store) MailboxOwners() ([]BHMailboxOwner, error) {
+	var res []BHMailboxOwner
+	err := s.bh.Find(&res, &badgerhold.Query{})
+	return res, err
+}
+
 func (s *Store) Close() {
 	s.bh.Close()
 }
\ No newline at end of file
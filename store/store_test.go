@@ -1,6 +1,7 @@
 package store_test
 
 import (
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -36,7 +37,7 @@ func TestContact(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	res, err := s.AllContacts(0, 10)
+	res, _, err := s.AllContacts(store.Seek{Limit: 10})
 	require.NoError(t, err)
 	if !reflect.DeepEqual(res, data) {
 		t.Error("in and out are not equal")
@@ -154,26 +155,32 @@ func TestChat(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	res, err := s.ChatList(0, 10)
+	res, _, err := s.ChatList(store.Seek{Limit: 10})
 	require.NoError(t, err)
-	if !reflect.DeepEqual(res, test_chat) {
-		t.Error("in and out are not equal")
-	}
+	// the latest active first, the summaries follow the messages
+	require.Len(t, res, 2)
+	require.Equal(t, "2", res[0].ID)
+	require.Equal(t, "3", res[0].Summary.LastMsgID)
+	require.Equal(t, uint64(2), res[0].Summary.Unread)
+	require.Equal(t, "1", res[1].Summary.LastMsgID)
+	require.Equal(t, test_msg[0].Text, res[1].Summary.LastText)
+	require.Equal(t, test_msg[0].Author, res[1].Summary.LastAuthor)
 
 	res2, err := s.ChatByID("1")
 	require.NoError(t, err)
+	test_chat[0].Summary = res[1].Summary
 	if !reflect.DeepEqual(res2, test_chat[0]) {
 		t.Error("in and out are not equal")
 	}
 
-	res3, err := s.ChatMessages("1", 0, 0)
+	res3, _, err := s.ChatMessages("1", store.Seek{})
 	require.NoError(t, err)
 	if !reflect.DeepEqual(res3, test_msg[:2]) {
 		t.Error("in and out are not equal")
 	}
 
 	// test unread
-	msgs, err := s.ChatMessages("2", 0, 0)
+	msgs, _, err := s.ChatMessages("2", store.Seek{})
 	require.NoError(t, err)
 	count, err := s.ChatUnreadCount("2")
 	require.NoError(t, err)
@@ -199,3 +206,166 @@ func TestIdentity(t *testing.T) {
 		t.Errorf("net equal %s, %s", res, expected)
 	}
 }
+
+func TestSearch(t *testing.T) {
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	blue := store.BHContact{ID: "1", Name: "blue"}
+	red := store.BHContact{ID: "2", Name: "red"}
+	msgs := []store.BHTextMessage{
+		{ID: "1", ChatID: "1", Author: blue, CreatedAt: 100, Text: "Lunch at noon?"},
+		{ID: "2", ChatID: "1", Author: red, CreatedAt: 200, Text: "lunch, lunch and more LUNCH"},
+		{ID: "3", ChatID: "2", Author: blue, CreatedAt: 300, Text: "no lunch today, noon meeting"},
+		{ID: "4", ChatID: "2", Author: red, CreatedAt: 400, Text: "the menu", Attachments: []store.BHAttachment{{Hash: "h", Name: "lunch-menu.pdf"}}},
+	}
+	for _, m := range msgs {
+		require.NoError(t, s.InsertTextMessage(m))
+	}
+	ids := func(q store.SearchQuery) []string {
+		hits, _, err := s.SearchMessages(q, store.Seek{})
+		require.NoError(t, err)
+		res := make([]string, 0)
+		for _, h := range hits {
+			res = append(res, h.Msg.ID)
+		}
+		return res
+	}
+
+	require.Equal(t, []store.Token{{Term: "lunch", Start: 0, End: 5}, {Term: "at", Start: 6, End: 8}, {Term: "noon", Start: 9, End: 13}}, store.Tokenize("Lunch at noon?"))
+	// repeated words rank first, ties go to the newest
+	require.Equal(t, []string{"2", "4", "3", "1"}, ids(store.SearchQuery{Terms: []string{"lunch"}}))
+	require.Equal(t, []string{"3", "1"}, ids(store.SearchQuery{Terms: []string{"lunch", "noon"}}))
+	require.Equal(t, []string{"1"}, ids(store.SearchQuery{Terms: []string{"lunch", "noon"}, ChatID: "1"}))
+	require.Equal(t, []string{"2", "4"}, ids(store.SearchQuery{Terms: []string{"lunch"}, Authors: []string{"2"}}))
+	require.Equal(t, []string{"2", "3"}, ids(store.SearchQuery{Terms: []string{"lunch"}, Since: 150, Until: 350}))
+	require.Empty(t, ids(store.SearchQuery{Terms: []string{"dinner"}}))
+
+	// pages go on from the score and id of a hit
+	lunch := store.SearchQuery{Terms: []string{"lunch"}}
+	hits, more, err := s.SearchMessages(lunch, store.Seek{Limit: 2})
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Len(t, hits, 2)
+	last := hits[1]
+	after := store.Seek{Key: int64(math.Float64bits(last.Score)), ID: last.Msg.ID, From: true, Limit: 2}
+	hits, more, err = s.SearchMessages(lunch, after)
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Equal(t, "3", hits[0].Msg.ID)
+	require.Equal(t, "1", hits[1].Msg.ID)
+	first := hits[0]
+	before := store.Seek{Key: int64(math.Float64bits(first.Score)), ID: first.Msg.ID, From: true, Before: true, Limit: 1}
+	hits, more, err = s.SearchMessages(lunch, before)
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Len(t, hits, 1)
+	require.Equal(t, "4", hits[0].Msg.ID)
+
+	// the index follows edits and deletes
+	edited := msgs[0]
+	edited.Text = "dinner at eight"
+	require.NoError(t, s.UpdateMessage(edited))
+	require.Equal(t, []string{"1"}, ids(store.SearchQuery{Terms: []string{"dinner"}}))
+	require.Equal(t, []string{"3"}, ids(store.SearchQuery{Terms: []string{"noon"}}))
+	deleted := msgs[2]
+	deleted.Text = ""
+	deleted.Deleted = true
+	require.NoError(t, s.UpdateMessage(deleted))
+	require.NoError(t, s.DeleteMessage("2"))
+	require.Equal(t, []string{"4"}, ids(store.SearchQuery{Terms: []string{"lunch"}}))
+}
+
+func TestChatSummary(t *testing.T) {
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	alice := store.BHContact{ID: "1", Name: "alice"}
+	bob := store.BHContact{ID: "2", Name: "bob"}
+	require.NoError(t, s.InsertChat(store.BHChat{ID: "1", Name: "bob", Members: []store.BHContact{alice, bob}, Type: entity.Private}))
+	require.NoError(t, s.InsertChat(store.BHChat{ID: "2", Name: "alice", Members: []store.BHContact{alice}, Type: entity.Private}))
+
+	summary := func(id string) store.BHChatSummary {
+		ch, err := s.ChatByID(id)
+		require.NoError(t, err)
+		unread, err := s.ChatUnreadCount(id)
+		require.NoError(t, err)
+		require.Equal(t, unread, ch.Summary.Unread)
+		return ch.Summary
+	}
+	// a late message does not take the place of a newer one
+	require.NoError(t, s.InsertTextMessage(store.BHTextMessage{ID: "b", ChatID: "1", CreatedAt: 200, Text: "second", Author: bob, Status: entity.Received}))
+	require.NoError(t, s.InsertTextMessage(store.BHTextMessage{ID: "a", ChatID: "1", CreatedAt: 100, Text: "first", Author: bob, Status: entity.Received}))
+	sum := summary("1")
+	require.Equal(t, "b", sum.LastMsgID)
+	require.Equal(t, "second", sum.LastText)
+	require.Equal(t, bob, sum.LastAuthor)
+	require.Equal(t, int64(200), sum.LastAt)
+	require.Equal(t, uint64(2), sum.Unread)
+
+	// a chat without messages is active from when it was added
+	chats, _, err := s.ChatList(store.Seek{})
+	require.NoError(t, err)
+	require.Equal(t, "2", chats[0].ID)
+	require.Equal(t, "1", chats[1].ID)
+
+	// seen messages are no longer unread, edits show in the summary
+	msg, err := s.MsgByID("b")
+	require.NoError(t, err)
+	msg.Status = entity.Seen
+	msg.Text = "second, edited"
+	require.NoError(t, s.UpdateMessage(msg))
+	sum = summary("1")
+	require.Equal(t, "second, edited", sum.LastText)
+	require.Equal(t, uint64(1), sum.Unread)
+
+	// changing the chat keeps its summary
+	ch, err := s.ChatByID("1")
+	require.NoError(t, err)
+	ch.Name = "bobby"
+	ch.Summary = store.BHChatSummary{}
+	require.NoError(t, s.UpdateChat(ch))
+	require.Equal(t, sum, summary("1"))
+
+	// the message before a deleted last message takes its place
+	require.NoError(t, s.DeleteMessage("b"))
+	sum = summary("1")
+	require.Equal(t, "a", sum.LastMsgID)
+	require.Equal(t, int64(100), sum.LastAt)
+	require.NoError(t, s.DeleteMessage("a"))
+	sum = summary("1")
+	require.Empty(t, sum.LastMsgID)
+	require.Empty(t, sum.LastText)
+	require.Equal(t, uint64(0), sum.Unread)
+
+	// a new message moves its chat first
+	require.NoError(t, s.InsertTextMessage(store.BHTextMessage{ID: "c", ChatID: "1", CreatedAt: time.Now().Unix() + 10, Text: "hi", Author: alice, Status: entity.Sent}))
+	chats, _, err = s.ChatList(store.Seek{})
+	require.NoError(t, err)
+	require.Equal(t, "1", chats[0].ID)
+	require.Equal(t, "2", chats[1].ID)
+
+	// a chat added after its messages sums them up
+	require.NoError(t, s.InsertTextMessage(store.BHTextMessage{ID: "d", ChatID: "3", CreatedAt: 300, Text: "early", Author: bob, Status: entity.Received}))
+	require.NoError(t, s.InsertChat(store.BHChat{ID: "3", Name: "bob", Members: []store.BHContact{alice, bob}, Type: entity.Private}))
+	sum = summary("3")
+	require.Equal(t, "d", sum.LastMsgID)
+	require.Equal(t, uint64(1), sum.Unread)
+}
+
+func TestSweeper(t *testing.T) {
+	s, err := store.NewStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.InsertTextMessage(store.BHTextMessage{ID: "1", ChatID: "1", CreatedAt: 100, Text: "gone", ExpiresAt: 200}))
+	started := make(chan struct{})
+	var done bool
+	stop := s.StartSweeper(10*time.Millisecond, func(msgs []store.BHTextMessage) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		done = true
+	})
+	<-started
+	// stop waits for the running sweep
+	stop()
+	require.True(t, done)
+	_, err = s.MsgByID("1")
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"github.com/dgraph-io/badger/v3"
+	"github.com/hood-chat/core/entity"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// BHChatSummary is kept with the messages of a chat so that chats are
+// listed without reading their messages.
+type BHChatSummary struct {
+	LastMsgID  string
+	LastText   string
+	LastAuthor BHContact
+	// creation time of the last message, or when the chat was added
+	LastAt int64
+	// received messages not seen yet
+	Unread uint64
+}
+
+// summarize updates the summary of the chat of a message written in tx,
+// old is the stored message, nil for a new message, and msg is nil for a
+// deleted message. Messages of unknown chats are skipped.
+func (s *Store) summarize(tx *badger.Txn, old *BHTextMessage, msg *BHTextMessage) error {
+	chatID := ""
+	if msg != nil {
+		chatID = msg.ChatID
+	} else {
+		chatID = old.ChatID
+	}
+	var ch BHChat
+	err := s.bh.TxGet(tx, chatID, &ch)
+	if err == badgerhold.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sum := &ch.Summary
+	if old != nil && old.Status == entity.Received && sum.Unread > 0 {
+		sum.Unread--
+	}
+	if msg != nil && msg.Status == entity.Received {
+		sum.Unread++
+	}
+	switch {
+	case msg != nil && (msg.ID == sum.LastMsgID || later(*msg, *sum)):
+		setLast(sum, msg)
+	case msg == nil && old.ID == sum.LastMsgID:
+		// the last message is gone, the one before it takes its place
+		var res []BHTextMessage
+		q := badgerhold.Where("ChatID").Eq(chatID).And("ID").Ne(old.ID)
+		err = s.bh.TxFind(tx, &res, q.SortBy("CreatedAt", "ID").Reverse().Limit(1))
+		if err != nil {
+			return err
+		}
+		if len(res) > 0 {
+			setLast(sum, &res[0])
+		} else {
+			setLast(sum, nil)
+		}
+	}
+	return s.bh.TxUpdate(tx, ch.ID, ch)
+}
+
+// resummarize computes the summary of ch from its stored messages, a chat
+// without messages is active from emptyAt.
+func (s *Store) resummarize(tx *badger.Txn, ch *BHChat, emptyAt int64) error {
+	var res []BHTextMessage
+	q := badgerhold.Where("ChatID").Eq(ch.ID)
+	err := s.bh.TxFind(tx, &res, q.SortBy("CreatedAt", "ID").Reverse().Limit(1))
+	if err != nil {
+		return err
+	}
+	ch.Summary = BHChatSummary{LastAt: emptyAt}
+	if len(res) > 0 {
+		setLast(&ch.Summary, &res[0])
+	}
+	ch.Summary.Unread, err = s.bh.TxCount(tx, &BHTextMessage{}, badgerhold.Where("ChatID").Eq(ch.ID).And("Status").Eq(entity.Received))
+	return err
+}
+
+// later tells whether msg is newer than the last message of sum.
+func later(msg BHTextMessage, sum BHChatSummary) bool {
+	if sum.LastMsgID == "" {
+		return true
+	}
+	if msg.CreatedAt != sum.LastAt {
+		return msg.CreatedAt > sum.LastAt
+	}
+	return msg.ID > sum.LastMsgID
+}
+
+// setLast makes msg the last message of sum, without a message the chat
+// keeps its place.
+func setLast(sum *BHChatSummary, msg *BHTextMessage) {
+	if msg == nil {
+		sum.LastMsgID, sum.LastText, sum.LastAuthor = "", "", BHContact{}
+		return
+	}
+	sum.LastMsgID = msg.ID
+	sum.LastText = msg.Text
+	sum.LastAuthor = msg.Author
+	sum.LastAt = msg.CreatedAt
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// DeleteExpired deletes the messages that expired at now with their
+// history, it returns the deleted messages.
+func (s *Store) DeleteExpired(now int64) ([]BHTextMessage, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var res []BHTextMessage
+	err := s.bh.Find(&res, badgerhold.Where("ExpiresAt").Gt(int64(0)).And("ExpiresAt").Le(now).Index("ExpiresAt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range res {
+		err = s.deleteMessage(msg.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = s.DeleteMessageVersions(msg.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// StartSweeper deletes the expired messages every interval until stop is
+// called, expired gets the messages of each sweep that deleted some. stop
+// returns when a running sweep is over.
+func (s *Store) StartSweeper(interval time.Duration, expired func([]BHTextMessage)) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				msgs, err := s.DeleteExpired(time.Now().Unix())
+				if err != nil {
+					log.Errorf("sweep expired messages: %s", err.Error())
+					continue
+				}
+				if len(msgs) > 0 {
+					expired(msgs)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
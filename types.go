@@ -2,9 +2,11 @@ package core
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/hood-chat/core/entity"
+	"github.com/hood-chat/core/pb"
 	"github.com/hood-chat/core/protocol"
 	"github.com/libp2p/go-libp2p/core/event"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -65,6 +67,23 @@ func NewGroupChat(n string, c []entity.Contact) NewChatOpt {
 	return NewChatOpt{Name: &n, Members: c, Type: Of(entity.Group)}
 }
 
+// SearchMessageOpt narrows a message search, unset fields do not filter.
+type SearchMessageOpt struct {
+	ChatID  *entity.ID  `json:"chatId,omitempty"`
+	Authors []entity.ID `json:"authors,omitempty"`
+	// unix times, inclusive
+	Since *int64 `json:"since,omitempty"`
+	Until *int64 `json:"until,omitempty"`
+}
+
+func (m *SearchMessageOpt) Json() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *SearchMessageOpt) DTO() SearchMessageOpt {
+	return *m
+}
+
 type ChatRequest struct {
 	ID          entity.ID
 	Name        string
@@ -79,9 +98,18 @@ type Envelop struct {
 	ID        string
 	CreatedAt int64
 	Protocol  pl.ID
+	// when the envelop was last handed to the service, the outbox times out
+	// from there so a resent envelop gets a full timeout
+	QueuedAt int64
+	// the message encrypted for the recipient on the first attempt, every
+	// later attempt and deposit sends it again
+	Sealed *pb.SecureMessage
 }
 
 func (e Envelop) createdAt() time.Time {
+	if e.QueuedAt > 0 {
+		return time.Unix(e.QueuedAt, 0)
+	}
 	return time.Unix(e.CreatedAt, 0)
 }
 func (e Envelop) id() string {
@@ -116,3 +144,17 @@ func NewMessageEnvelop(c entity.Contact, m entity.Message) (*Envelop, error) {
 		Protocol: protocol.Message.ID(),
 	}, nil
 }
+
+func NewReceiptEnvelop(c entity.Contact, r entity.Receipt) (*Envelop, error) {
+	_, err := c.PeerID()
+	if err != nil {
+		return nil, err
+	}
+	return &Envelop{
+		To:        c,
+		Message:   r,
+		ID:        fmt.Sprintf("%s:%d", r.MsgID, r.Status),
+		CreatedAt: time.Now().Unix(),
+		Protocol:  protocol.ChatEvent.ID(),
+	}, nil
+}